@@ -2,26 +2,81 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"protonvpn-wg-confgen/internal/api"
 	"protonvpn-wg-confgen/internal/auth"
+	"protonvpn-wg-confgen/internal/cli"
 	"protonvpn-wg-confgen/internal/config"
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/internal/keystore"
 	"protonvpn-wg-confgen/internal/vpn"
+	"protonvpn-wg-confgen/pkg/keys"
+	"protonvpn-wg-confgen/pkg/qrcode"
+	"protonvpn-wg-confgen/pkg/timeutil"
 	"protonvpn-wg-confgen/pkg/wireguard"
 
-	"github.com/ProtonVPN/go-vpn-lib/ed25519"
+	"golang.org/x/term"
 )
 
+// QR code settings: low error correction keeps the code small enough to scan from a screen
+const (
+	qrLevel = qrcode.Low
+	qrScale = 8 // Pixels (PNG) or units (SVG) per module
+)
+
+// errUnchanged is returned in -renew mode when no configuration had to be rewritten
+var errUnchanged = errors.New("configuration unchanged")
+
+// commands maps subcommand names to their implementations
+var commands = map[string]func(args []string) error{
+	"agent":        cli.RunAgent,
+	"check-expiry": cli.RunCheckExpiry,
+	"devices":      cli.RunDevices,
+	"inspect":      cli.RunInspect,
+	"portforward":  cli.RunPortForward,
+}
+
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if command, ok := lookupCommand(os.Args[1:]); ok {
+		err = command(os.Args[2:])
+	} else {
+		err = run()
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if errors.Is(err, errUnchanged) {
+		os.Exit(constants.ExitCodeUnchanged)
+	}
+	var exitErr *cli.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.Code)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(constants.ExitCodeError)
 	}
 }
 
+// lookupCommand returns the subcommand named by the first argument, if any
+func lookupCommand(args []string) (func(args []string) error, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	command, ok := commands[args[0]]
+	return command, ok
+}
+
 func run() error {
 	// Parse configuration
 	cfg, err := config.Parse()
@@ -30,6 +85,24 @@ func run() error {
 		return err
 	}
 
+	if cfg.ActiveProfile != "" {
+		fmt.Printf("Using selection profile: %s\n", cfg.ActiveProfile)
+	}
+	if cfg.CertPolicy != "" {
+		fmt.Printf("Using certificate policy: %s (%s, %s)\n", cfg.CertPolicy, cfg.CertMode, cfg.Duration)
+	}
+
+	// Check the output formats before authenticating so that a typo fails fast
+	if _, err := wireguard.ResolveOutputs(cfg); err != nil {
+		return err
+	}
+
+	// Read an imported key before authenticating so that a bad key fails fast
+	importedKey, err := loadImportedKey(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Authenticate
 	authClient := auth.NewClient(cfg)
 	session, err := authClient.Authenticate()
@@ -38,20 +111,19 @@ func run() error {
 	}
 	fmt.Println("Authentication successful!")
 
-	// Generate key pair
-	keyPair, err := ed25519.NewKeyPair()
-	if err != nil {
-		return fmt.Errorf("failed to generate key pair: %w", err)
-	}
-	cfg.ClientPrivateKey = keyPair.ToX25519Base64()
-
 	// Create VPN client
 	vpnClient := vpn.NewClient(cfg, session)
 
-	// Get VPN certificate
-	vpnInfo, err := vpnClient.GetCertificate(keyPair)
+	// Get the account's VPN plan to restrict selection to usable tiers
+	account, err := vpnClient.GetAccount()
 	if err != nil {
-		return fmt.Errorf("failed to get VPN certificate: %w", err)
+		fmt.Printf("Warning: Failed to get VPN plan, continuing without plan-based tier filtering: %v\n", err)
+	} else {
+		fmt.Printf("VPN plan: %s (max tier: %s, max connections: %d)\n",
+			account.GetPlanName(), api.GetTierName(account.MaxTier), account.MaxConnect)
+		if err := vpn.ApplyAccountTier(cfg, account); err != nil {
+			return err
+		}
 	}
 
 	// Get server list
@@ -60,12 +132,184 @@ func run() error {
 		return fmt.Errorf("failed to get servers: %w", err)
 	}
 
-	// Select best server
+	if cfg.ListAllServers {
+		printAllServers(servers)
+		return nil
+	}
+
+	// Select servers for all devices before registering any of them
 	selector := vpn.NewServerSelector(cfg)
-	server, err := selector.SelectBest(servers)
+	if account != nil {
+		selector.SetAccount(account)
+	}
+	deviceConfigs := cfg.DeviceConfigs()
+	storedDevices := loadStoredDevices(deviceConfigs, importedKey)
+	assignments, err := selectServers(cfg, selector, servers, storedDevices)
 	if err != nil {
 		return err
 	}
+	for _, assignment := range assignments {
+		if err := vpn.ValidateServerFeatures(cfg, assignment.server); err != nil {
+			return err
+		}
+	}
+
+	// Check the plan's connection limit before registering new devices
+	// (renewals re-register devices that are already counted)
+	if !cfg.Renew {
+		if err := checkConnectionUsage(cfg, vpnClient, account, countNewKeys(storedDevices)); err != nil {
+			return err
+		}
+	}
+
+	gen := &generation{session: session, selector: selector, servers: servers, importedKey: importedKey}
+	written := 0
+	for i, deviceCfg := range deviceConfigs {
+		if len(deviceConfigs) > 1 {
+			fmt.Printf("\n[%d/%d] Device %s\n", i+1, len(deviceConfigs), deviceCfg.DeviceName)
+		}
+		changed, err := generateDevice(deviceCfg, gen, assignments[i])
+		if err != nil {
+			return err
+		}
+		if changed {
+			written++
+		}
+	}
+
+	if cfg.Renew && written == 0 {
+		fmt.Println("\nNo configuration changed")
+		return errUnchanged
+	}
+
+	return nil
+}
+
+// checkConnectionUsage prints the account's connection usage and applies -connection-limit.
+// The check is skipped when the plan is unknown or the sessions cannot be fetched.
+func checkConnectionUsage(cfg *config.Config, vpnClient *vpn.Client, account *api.VPNAccount, newDevices int) error {
+	if cfg.ConnectionLimit == constants.ConnectionLimitIgnore || account == nil {
+		return nil
+	}
+
+	sessions, err := vpnClient.GetSessions()
+	if err != nil {
+		fmt.Printf("Warning: Failed to get active VPN sessions, skipping connection limit check: %v\n", err)
+		return nil
+	}
+
+	usage := vpn.ConnectionUsage{Active: len(sessions), Max: account.MaxConnect, NewDevices: newDevices}
+	fmt.Printf("Connection usage: %s\n", usage)
+
+	return vpn.CheckConnectionLimit(cfg, usage)
+}
+
+// storedDevice is what the key store holds for a device before the run
+type storedDevice struct {
+	newKey     bool   // The device will register a new key, adding a device to the account
+	physicalID string // Physical server of the stored configuration, kept by -renew when still usable
+}
+
+// loadStoredDevices looks up the stored key of each device, without registering anything
+func loadStoredDevices(deviceConfigs []*config.Config, importedKey *keys.KeyPair) []storedDevice {
+	devices := make([]storedDevice, len(deviceConfigs))
+	for i, cfg := range deviceConfigs {
+		if cfg.NoKeyStore || cfg.RotateKey {
+			devices[i].newKey = true
+			continue
+		}
+
+		store := keystore.NewStore(cfg.KeyStoreDir, os.Getenv(constants.KeyPassphraseEnv))
+		entry, keyPair, err := store.Load(cfg.Username, cfg.KeyProfileName())
+		if err != nil || keyPair == nil ||
+			(importedKey != nil && keyPair.ToX25519Base64() != importedKey.ToX25519Base64()) {
+			devices[i].newKey = true
+			continue
+		}
+		devices[i].physicalID = entry.Server.PhysicalServerID
+	}
+	return devices
+}
+
+// countNewKeys returns the number of devices that will register a new key. Devices that
+// reuse or re-register their stored key are already counted against the connection limit.
+func countNewKeys(devices []storedDevice) int {
+	count := 0
+	for _, device := range devices {
+		if device.newKey {
+			count++
+		}
+	}
+	return count
+}
+
+// generation holds the state shared by all devices of a run
+type generation struct {
+	session     *api.Session
+	selector    *vpn.ServerSelector
+	servers     []api.LogicalServer
+	importedKey *keys.KeyPair // Set with -private-key-file or -private-key-env
+}
+
+// loadImportedKey reads the key given with -private-key-file or -private-key-env, if any
+func loadImportedKey(cfg *config.Config) (*keys.KeyPair, error) {
+	var data []byte
+	var err error
+	switch {
+	case cfg.PrivateKeyFile == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case cfg.PrivateKeyFile != "":
+		data, err = os.ReadFile(cfg.PrivateKeyFile)
+	case cfg.PrivateKeyEnv != "":
+		value, ok := os.LookupEnv(cfg.PrivateKeyEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", cfg.PrivateKeyEnv)
+		}
+		data = []byte(value)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	keyPair, err := keys.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid imported private key: %w", err)
+	}
+	return keyPair, nil
+}
+
+// serverAssignment pairs a logical server with the physical server a device connects to
+type serverAssignment struct {
+	server         *api.LogicalServer
+	physicalServer *api.PhysicalServer
+}
+
+// generateDevice obtains the device's key and certificate and writes its WireGuard configuration.
+// In -renew mode the assignment keeps the previously configured server while it remains usable
+// (see selectServers), and the configuration is only rewritten if the server, key or requested features changed; the result
+// reports whether it was.
+func generateDevice(cfg *config.Config, gen *generation, assignment serverAssignment) (bool, error) {
+	// Get the device key pair and certificate, reusing a stored key when possible
+	identity, err := obtainCertificate(cfg, gen.session, gen.importedKey)
+	if err != nil {
+		return false, err
+	}
+	cfg.ClientPrivateKey = identity.keyPair.ToX25519Base64()
+	cfg.DeviceName = identity.deviceName
+	deviceName := identity.deviceName
+	server, physicalServer := assignment.server, assignment.physicalServer
+
+	outputs, err := wireguard.OutputPaths(cfg)
+	if err != nil {
+		return false, err
+	}
+	if cfg.Renew && !identity.rewrite && identity.entry.Server.Matches(physicalServer, outputs) && filesExist(outputs) {
+		fmt.Printf("Configuration %s is up to date (server %s, certificate refresh in %s)\n", strings.Join(outputs, ", "), server.Name,
+			timeutil.HumanizeDuration(time.Until(identity.entry.Certificate.RefreshAt())))
+		return false, nil
+	}
 
 	// Build feature list string
 	features := api.GetFeatureNames(server.Features)
@@ -74,46 +318,319 @@ func run() error {
 		featureStr = fmt.Sprintf(", Features: %s", strings.Join(features, ", "))
 	}
 
-	fmt.Printf("Selected server: %s (Country: %s, City: %s, Tier: %s, Load: %d%%, Score: %.2f, Servers: %d%s)\n",
-		server.Name, server.ExitCountry, server.City, api.GetTierName(server.Tier),
+	selectedLabel := "Selected"
+	if cfg.HasPinnedServer() {
+		selectedLabel = "Pinned"
+	}
+
+	fmt.Printf("%s server: %s (Country: %s, City: %s, Tier: %s, Load: %d%%, Score: %.2f, Servers: %d%s)\n",
+		selectedLabel, server.Name, server.ExitCountry, server.City, api.GetTierName(server.Tier),
 		server.Load, server.Score, len(server.Servers), featureStr)
 
-	// Get best physical server
-	physicalServer := vpn.GetBestPhysicalServer(server)
-	if physicalServer == nil {
-		return fmt.Errorf("no physical servers available")
+	// Echo the features actually granted in the certificate
+	certFeatures := api.GetCertificateFeatureNames(identity.certificate.Features)
+	if len(certFeatures) > 0 {
+		fmt.Printf("Certificate features: %s\n", strings.Join(certFeatures, ", "))
+	} else {
+		fmt.Println("Certificate features: none")
+	}
+
+	group, err := selectGroup(cfg, gen, server)
+	if err != nil {
+		return false, err
 	}
 
 	// Generate WireGuard configuration
 	generator := wireguard.NewConfigGenerator(cfg)
+	generator.SetGroupServers(group)
+	generator.SetCertificateFeatures(certFeatures)
+	if identity.certificate.ExpirationTime > 0 {
+		generator.SetCertificateExpiry(identity.certificate.ExpiresAt())
+	}
 	if err := generator.Generate(server, physicalServer, cfg.ClientPrivateKey); err != nil {
-		return fmt.Errorf("failed to generate WireGuard config: %w", err)
+		return false, fmt.Errorf("failed to generate WireGuard config: %w", err)
 	}
 
-	fmt.Printf("WireGuard configuration written to: %s\n", cfg.OutputFile)
+	for _, path := range outputs {
+		fmt.Printf("WireGuard configuration written to: %s\n", path)
+	}
+
+	if cfg.QR != "" {
+		if err := writeQR(cfg, generator, server, physicalServer); err != nil {
+			return false, err
+		}
+	}
+
+	// Remember the server so renewals can tell whether the config must be rewritten
+	if identity.entry != nil {
+		identity.entry.SetServer(server, physicalServer, outputs)
+		if err := identity.store.Save(identity.entry, identity.keyPair); err != nil {
+			fmt.Printf("Warning: Failed to save device metadata: %v\n", err)
+		}
+	}
 
 	// Note about persistence
-	if vpnInfo.DeviceName != "" {
-		fmt.Printf("Device name: %s (visible in ProtonVPN dashboard)\n", vpnInfo.DeviceName)
+	if deviceName != "" {
+		fmt.Printf("Device name: %s (visible in ProtonVPN dashboard)\n", deviceName)
 	}
 
 	// Show final success
 	fmt.Printf("\nSuccessfully generated config for %s\n", server.ExitCountry)
 
+	return true, nil
+}
+
+// writeQR shows the wg-quick configuration as a QR code for the WireGuard mobile apps. The
+// metadata comments are left out with -qr-strip, or when the configuration does not fit otherwise.
+func writeQR(cfg *config.Config, generator *wireguard.ConfigGenerator, server *api.LogicalServer, physicalServer *api.PhysicalServer) error {
+	content, err := generator.Render(wireguard.FormatWGQuick, server, physicalServer, cfg.ClientPrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to render QR code content: %w", err)
+	}
+	if cfg.QRStrip {
+		content = wireguard.StripComments(content)
+	}
+
+	code, err := qrcode.Encode(content, qrLevel)
+	var tooLong *qrcode.TooLongError
+	if errors.As(err, &tooLong) && !cfg.QRStrip {
+		fmt.Printf("Warning: The configuration with its metadata comments is %d bytes, more than a QR code holds (%d bytes); leaving the comments out (use -qr-strip to always do so)\n",
+			tooLong.Length, tooLong.Capacity)
+		code, err = qrcode.Encode(wireguard.StripComments(content), qrLevel)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(cfg.QR)) {
+	case ".png":
+		image, err := code.PNG(qrScale)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(cfg.QR, image, 0o600); err != nil {
+			return fmt.Errorf("failed to write QR code: %w", err)
+		}
+	case ".svg":
+		if err := os.WriteFile(cfg.QR, code.SVG(qrScale), 0o600); err != nil {
+			return fmt.Errorf("failed to write QR code: %w", err)
+		}
+	default:
+		width := code.Size() + 2*qrcode.QuietZone
+		if columns, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && columns < width {
+			fmt.Printf("Warning: The QR code is %d columns wide but the terminal has %d; widen the terminal or use -qr-strip\n", width, columns)
+		}
+		fmt.Printf("\nScan with the WireGuard app (Add tunnel > Create from QR code):\n%s\n", code.Terminal())
+		return nil
+	}
+
+	fmt.Printf("QR code (version %d) written to: %s\n", code.Version, cfg.QR)
 	return nil
 }
 
+// filesExist reports whether all paths exist
+func filesExist(paths []string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// deviceIdentity is a device's key pair and, unless -no-key-store is set, its key store entry
+type deviceIdentity struct {
+	keyPair     *keys.KeyPair
+	deviceName  string
+	certificate keystore.CertificateInfo // Metadata of the registered certificate
+	rewrite     bool                     // The config must be rewritten even if the server is unchanged
+	store       *keystore.Store
+	entry       *keystore.Entry
+}
+
+// obtainCertificate returns the key pair for the device and its registered certificate metadata.
+// A stored key is reused as long as its certificate is valid; it is re-registered when the
+// certificate nears its refresh or expiration time, and replaced on -rotate-key or when a
+// different key is imported.
+func obtainCertificate(cfg *config.Config, session *api.Session, importedKey *keys.KeyPair) (*deviceIdentity, error) {
+	vpnClient := vpn.NewClient(cfg, session)
+
+	if cfg.NoKeyStore {
+		keyPair := importedKey
+		if keyPair == nil {
+			var err error
+			if keyPair, err = keys.Generate(); err != nil {
+				return nil, fmt.Errorf("failed to generate key pair: %w", err)
+			}
+		}
+		vpnInfo, err := vpnClient.GetCertificate(keyPair)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VPN certificate: %w", err)
+		}
+		return &deviceIdentity{
+			keyPair:     keyPair,
+			deviceName:  vpnInfo.DeviceName,
+			certificate: keystore.NewCertificateInfo(vpnInfo, vpn.RequestedFeatures(cfg)),
+			rewrite:     true,
+		}, nil
+	}
+
+	store := keystore.NewStore(cfg.KeyStoreDir, os.Getenv(constants.KeyPassphraseEnv))
+	profile := cfg.KeyProfileName()
+
+	entry, keyPair, err := store.Load(cfg.Username, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored key for profile %s: %w", profile, err)
+	}
+	identity := &deviceIdentity{keyPair: keyPair, store: store, entry: entry}
+	requested := vpn.RequestedFeatures(cfg)
+
+	switch {
+	case importedKey != nil && (keyPair == nil || keyPair.ToX25519Base64() != importedKey.ToX25519Base64()):
+		fmt.Printf("Importing private key for profile %s\n", profile)
+		identity.keyPair = importedKey
+		identity.rewrite = true
+		identity.entry = &keystore.Entry{Profile: profile, Username: cfg.Username}
+
+	case entry == nil || cfg.RotateKey:
+		if entry != nil {
+			fmt.Printf("Rotating key for profile %s\n", profile)
+		}
+		identity.keyPair, err = keys.Generate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key pair: %w", err)
+		}
+		identity.rewrite = true
+		identity.entry = &keystore.Entry{Profile: profile, Username: cfg.Username}
+
+	case entry.Certificate.Requested != requested || (entry.Certificate.Mode != "" && entry.Certificate.Mode != cfg.CertMode):
+		fmt.Printf("Requested certificate mode or features changed for profile %s, re-registering stored key\n", profile)
+		if cfg.DeviceName == "" {
+			cfg.DeviceName = entry.Certificate.DeviceName
+		}
+		identity.rewrite = true
+
+	case entry.Certificate.NeedsRenewal(time.Now()):
+		fmt.Printf("Certificate for profile %s needs renewal, re-registering stored key\n", profile)
+		if cfg.DeviceName == "" {
+			cfg.DeviceName = entry.Certificate.DeviceName
+		}
+
+	default:
+		fmt.Printf("Reusing stored key for profile %s (certificate refresh in %s)\n",
+			profile, timeutil.HumanizeDuration(time.Until(entry.Certificate.RefreshAt())))
+		identity.deviceName = entry.Certificate.DeviceName
+		identity.certificate = entry.Certificate
+		return identity, nil
+	}
+
+	vpnInfo, err := vpnClient.GetCertificate(identity.keyPair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VPN certificate: %w", err)
+	}
+	identity.deviceName = vpnInfo.DeviceName
+
+	identity.entry.SetCertificate(vpnInfo, requested)
+	identity.certificate = identity.entry.Certificate
+	if err := store.Save(identity.entry, identity.keyPair); err != nil {
+		fmt.Printf("Warning: Failed to save device key: %v\n", err)
+	}
+
+	return identity, nil
+}
+
+// selectServers assigns a server to each device: the pinned server if one was requested,
+// otherwise servers from the ranked list honouring -distinct. In -renew mode devices keep
+// their stored server while it remains usable and distinct.
+func selectServers(cfg *config.Config, selector *vpn.ServerSelector, servers []api.LogicalServer, devices []storedDevice) ([]serverAssignment, error) {
+	count := len(devices)
+	assignments := make([]serverAssignment, 0, count)
+
+	if cfg.HasPinnedServer() {
+		server, physicalServer, err := selector.SelectPinned(servers)
+		if err != nil {
+			return nil, err
+		}
+		for range count {
+			assignments = append(assignments, serverAssignment{server: server, physicalServer: physicalServer})
+		}
+		return assignments, nil
+	}
+
+	if cfg.Renew {
+		stored := make([]string, count)
+		for i := range devices {
+			stored[i] = devices[i].physicalID
+		}
+		selected, physicalServers, err := selector.SelectRenewal(servers, stored)
+		if err != nil {
+			return nil, err
+		}
+		for i := range selected {
+			assignments = append(assignments, serverAssignment{server: selected[i], physicalServer: physicalServers[i]})
+		}
+		return assignments, nil
+	}
+
+	selected, err := selector.SelectN(servers, count)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range selected {
+		physicalServer := vpn.GetBestPhysicalServer(server)
+		if physicalServer == nil {
+			return nil, fmt.Errorf("no physical servers available for %s", server.Name)
+		}
+		assignments = append(assignments, serverAssignment{server: server, physicalServer: physicalServer})
+	}
+
+	return assignments, nil
+}
+
+// selectGroup returns the servers added after the selected one to the -group of the proxy formats
+func selectGroup(cfg *config.Config, gen *generation, server *api.LogicalServer) ([]wireguard.GroupServer, error) {
+	if cfg.GroupSize <= 1 {
+		return nil, nil
+	}
+
+	selected, err := gen.selector.SelectGroup(gen.servers, cfg.GroupSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select group servers: %w", err)
+	}
+
+	group := make([]wireguard.GroupServer, 0, cfg.GroupSize-1)
+	names := []string{server.Name}
+	for _, candidate := range selected {
+		if candidate.Name == server.Name || len(group) == cfg.GroupSize-1 {
+			continue
+		}
+		physicalServer := vpn.GetBestPhysicalServer(candidate)
+		if physicalServer == nil {
+			continue
+		}
+		group = append(group, wireguard.GroupServer{Server: candidate, PhysicalServer: physicalServer})
+		names = append(names, candidate.Name)
+	}
+
+	if len(names) < cfg.GroupSize {
+		fmt.Printf("Warning: Only %d of %d servers available for the group\n", len(names), cfg.GroupSize)
+	}
+	fmt.Printf("Group servers: %s\n", strings.Join(names, ", "))
+	return group, nil
+}
+
 // printAllServers prints detailed information about every logical server and its physical servers.
 func printAllServers(servers []api.LogicalServer) {
-    fmt.Printf("Total logical servers from API: %d\n\n", len(servers))
-    for _, ls := range servers {
-        feat := api.GetFeatureNames(ls.Features)
-        fmt.Printf("Logical: %s | ExitCountry: %s | City: %s | Tier: %s | Status: %s | Features: %v | #Physical: %d\n",
-            ls.Name, ls.ExitCountry, ls.City, api.GetTierName(ls.Tier), ls.Status, feat, len(ls.Servers))
-        for i, ps := range ls.Servers {
-            fmt.Printf("  [%d] Physical ID: %s | EntryIP: %s | ExitIP: %s | PublicKey: %s | Status: %s\n",
-                i+1, ps.ID, ps.EntryIP, ps.ExitIP, ps.X25519PublicKey, ps.Status)
-        }
-        fmt.Println("--------------------------------------------------------------------------------")
-    }
+	fmt.Printf("Total logical servers from API: %d\n\n", len(servers))
+	for _, ls := range servers {
+		feat := api.GetFeatureNames(ls.Features)
+		fmt.Printf("Logical: %s | ExitCountry: %s | City: %s | Tier: %s | Status: %d | Features: %v | #Physical: %d\n",
+			ls.Name, ls.ExitCountry, ls.City, api.GetTierName(ls.Tier), ls.Status, feat, len(ls.Servers))
+		for i, ps := range ls.Servers {
+			fmt.Printf("  [%d] Physical ID: %s | EntryIP: %s | ExitIP: %s | PublicKey: %s | Status: %d\n",
+				i+1, ps.ID, ps.EntryIP, ps.ExitIP, ps.X25519PublicKey, ps.Status)
+		}
+		fmt.Println("--------------------------------------------------------------------------------")
+	}
 }
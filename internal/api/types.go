@@ -1,6 +1,8 @@
 // Package api defines the data structures for ProtonVPN API responses.
 package api
 
+import "fmt"
+
 // AuthInfoResponse represents the response from the auth info endpoint
 type AuthInfoResponse struct {
 	Code            int    `json:"Code"`
@@ -45,25 +47,47 @@ type Session struct {
 
 // VPNInfo represents VPN certificate information
 type VPNInfo struct {
-	Code                 int    `json:"Code"`
-	Error                string `json:"Error,omitempty"`
-	SerialNumber         string `json:"SerialNumber"`
-	ClientKeyFingerprint string `json:"ClientKeyFingerprint"`
-	ClientKey            string `json:"ClientKey"`
-	Certificate          string `json:"Certificate"`
-	ExpirationTime       int64  `json:"ExpirationTime"`
-	RefreshTime          int64  `json:"RefreshTime"`
-	Mode                 string `json:"Mode"`
-	DeviceName           string `json:"DeviceName"`
-	ServerPublicKeyMode  string `json:"ServerPublicKeyMode"`
-	ServerPublicKey      string `json:"ServerPublicKey"`
-	Features             struct {
-		Bouncing       bool `json:"bouncing"`
-		ModerateNAT    bool `json:"moderate-nat"`
-		NetshieldLevel int  `json:"netshield-level"`
-		PortForwarding bool `json:"port-forwarding"`
-		VPNAccelerator bool `json:"vpn-accelerator"`
-	} `json:"Features"`
+	Code                 int                 `json:"Code"`
+	Error                string              `json:"Error,omitempty"`
+	SerialNumber         string              `json:"SerialNumber"`
+	ClientKeyFingerprint string              `json:"ClientKeyFingerprint"`
+	ClientKey            string              `json:"ClientKey"`
+	Certificate          string              `json:"Certificate"`
+	ExpirationTime       int64               `json:"ExpirationTime"`
+	RefreshTime          int64               `json:"RefreshTime"`
+	Mode                 string              `json:"Mode"`
+	DeviceName           string              `json:"DeviceName"`
+	ServerPublicKeyMode  string              `json:"ServerPublicKeyMode"`
+	ServerPublicKey      string              `json:"ServerPublicKey"`
+	CreationTime         int64               `json:"CreationTime,omitempty"`
+	Features             CertificateFeatures `json:"Features"`
+}
+
+// CertificateFeatures represents the features granted in a VPN certificate
+type CertificateFeatures struct {
+	Bouncing       bool `json:"bouncing"`
+	ModerateNAT    bool `json:"moderate-nat"`
+	NetshieldLevel int  `json:"netshield-level"`
+	PortForwarding bool `json:"port-forwarding"`
+	VPNAccelerator bool `json:"vpn-accelerator"`
+}
+
+// CertificateFeaturesRequest represents the features requested for a VPN certificate.
+// Feature keys from: python-proton-vpn-api-core/proton/vpn/session/fetcher.py
+type CertificateFeaturesRequest struct {
+	NetShieldLevel int  `json:"NetShieldLevel"`
+	RandomNAT      bool `json:"RandomNAT"`      // Strict NAT; false enables Moderate NAT
+	PortForwarding bool `json:"PortForwarding"` // Port forwarding
+	SplitTCP       bool `json:"SplitTCP"`       // VPN Accelerator
+	Bouncing       bool `json:"Bouncing,omitempty"`
+}
+
+// CertificatesResponse represents the response from the certificate list endpoint
+type CertificatesResponse struct {
+	Code         int       `json:"Code"`
+	Error        string    `json:"Error,omitempty"`
+	Certificates []VPNInfo `json:"Certificates"`
+	Total        int       `json:"Total"`
 }
 
 // LogicalServer represents a ProtonVPN logical server
@@ -107,6 +131,56 @@ type LogicalsResponse struct {
 	LogicalServers []LogicalServer `json:"LogicalServers"`
 }
 
+// VPNAccountResponse represents the response from the VPN account endpoint
+type VPNAccountResponse struct {
+	Code       int        `json:"Code"`
+	Error      string     `json:"Error,omitempty"`
+	VPN        VPNAccount `json:"VPN"`
+	Delinquent int        `json:"Delinquent"`
+}
+
+// VPNAccount describes the VPN plan of the authenticated account
+type VPNAccount struct {
+	Status         int    `json:"Status"`
+	PlanName       string `json:"PlanName"`
+	PlanTitle      string `json:"PlanTitle"`
+	MaxTier        int    `json:"MaxTier"`
+	MaxConnect     int    `json:"MaxConnect"`
+	ExpirationTime int64  `json:"ExpirationTime"`
+}
+
+// IsFree reports whether the account only has access to Free tier servers
+func (a *VPNAccount) IsFree() bool {
+	return a.MaxTier == TierFree
+}
+
+// GetPlanName returns a human-readable plan name
+func (a *VPNAccount) GetPlanName() string {
+	switch {
+	case a.PlanTitle != "":
+		return a.PlanTitle
+	case a.PlanName != "":
+		return a.PlanName
+	case a.IsFree():
+		return "Free"
+	default:
+		return "Unknown"
+	}
+}
+
+// VPNSessionsResponse represents the response from the VPN sessions endpoint
+type VPNSessionsResponse struct {
+	Code     int          `json:"Code"`
+	Sessions []VPNSession `json:"Sessions"`
+}
+
+// VPNSession represents an active VPN connection of the account
+type VPNSession struct {
+	SessionID string `json:"SessionID"`
+	ExitIP    string `json:"ExitIP"`
+	Protocol  string `json:"Protocol"`
+}
+
 // Server feature constants
 const (
 	FeatureSecureCore = 1
@@ -163,3 +237,24 @@ func GetFeatureNames(features int) []string {
 	}
 	return result
 }
+
+// GetCertificateFeatureNames returns a list of features granted in a VPN certificate
+func GetCertificateFeatureNames(features CertificateFeatures) []string {
+	var result []string
+	if features.NetshieldLevel > 0 {
+		result = append(result, fmt.Sprintf("NetShield (level %d)", features.NetshieldLevel))
+	}
+	if features.ModerateNAT {
+		result = append(result, "Moderate NAT")
+	}
+	if features.PortForwarding {
+		result = append(result, "Port Forwarding")
+	}
+	if features.VPNAccelerator {
+		result = append(result, "VPN Accelerator")
+	}
+	if features.Bouncing {
+		result = append(result, "Bouncing")
+	}
+	return result
+}
@@ -28,6 +28,7 @@ type Client struct {
 	config       *config.Config
 	httpClient   *http.Client
 	sessionStore *SessionStore
+	output       io.Writer // Progress messages and prompts
 }
 
 // NewClient creates a new authentication client
@@ -35,6 +36,7 @@ func NewClient(cfg *config.Config) *Client {
 	return &Client{
 		config:       cfg,
 		sessionStore: NewSessionStore(),
+		output:       os.Stdout,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
@@ -47,29 +49,34 @@ func NewClient(cfg *config.Config) *Client {
 	}
 }
 
+// SetOutput sets the writer for progress messages and prompts (stdout by default)
+func (c *Client) SetOutput(w io.Writer) {
+	c.output = w
+}
+
 // handleSessionRefresh attempts to refresh a session and save it if successful
 func (c *Client) handleSessionRefresh(savedSession *api.Session, reason string) (*api.Session, error) {
-	fmt.Println(reason)
+	fmt.Fprintln(c.output, reason)
 	refreshedSession, err := RefreshSession(c.httpClient, c.config.APIURL, savedSession)
 	if err != nil {
-		fmt.Printf("Token refresh failed: %v\n", err)
-		fmt.Println("Re-authenticating with password...")
-		fmt.Println("(Your trusted device status for MFA will be preserved)")
+		fmt.Fprintf(c.output, "Token refresh failed: %v\n", err)
+		fmt.Fprintln(c.output, "Re-authenticating with password...")
+		fmt.Fprintln(c.output, "(Your trusted device status for MFA will be preserved)")
 		_ = c.sessionStore.Delete()
 		return nil, err
 	}
 
-	fmt.Println("Session refreshed successfully!")
+	fmt.Fprintln(c.output, "Session refreshed successfully!")
 	// Check if refresh token was rotated
 	if savedSession.RefreshToken != refreshedSession.RefreshToken {
-		fmt.Println("Refresh token was rotated")
+		fmt.Fprintln(c.output, "Refresh token was rotated")
 	}
 
 	// Save the refreshed session
 	if !c.config.NoSession {
 		sessionDuration, _ := timeutil.ParseSessionDuration(c.config.SessionDuration)
 		if err := c.sessionStore.Save(refreshedSession, c.config.Username, sessionDuration); err != nil {
-			fmt.Printf("Warning: Failed to save refreshed session: %v\n", err)
+			fmt.Fprintf(c.output, "Warning: Failed to save refreshed session: %v\n", err)
 		}
 	}
 
@@ -80,7 +87,7 @@ func (c *Client) handleSessionRefresh(savedSession *api.Session, reason string)
 func (c *Client) tryExistingSession() (*api.Session, error) {
 	savedSession, timeUntilExpiry, err := c.sessionStore.Load(c.config.Username)
 	if err != nil {
-		fmt.Printf("Warning: Failed to load saved session: %v\n", err)
+		fmt.Fprintf(c.output, "Warning: Failed to load saved session: %v\n", err)
 		return nil, err
 	}
 
@@ -99,11 +106,11 @@ func (c *Client) tryExistingSession() (*api.Session, error) {
 		return c.handleSessionRefresh(savedSession, reason)
 
 	case VerifySession(c.httpClient, c.config.APIURL, savedSession):
-		fmt.Printf("Using saved session (expires in %s)\n", timeutil.HumanizeDuration(timeUntilExpiry))
+		fmt.Fprintf(c.output, "Using saved session (expires in %s)\n", timeutil.HumanizeDuration(timeUntilExpiry))
 		return savedSession, nil
 
 	default:
-		fmt.Println("Saved session invalid, re-authenticating...")
+		fmt.Fprintln(c.output, "Saved session invalid, re-authenticating...")
 		_ = c.sessionStore.Delete()
 		return nil, nil
 	}
@@ -142,7 +149,7 @@ func (c *Client) Authenticate() (*api.Session, error) {
 // handleExistingSession handles session clearing or reuse
 func (c *Client) handleExistingSession() *api.Session {
 	if c.config.ClearSession {
-		fmt.Println("Clearing saved session...")
+		fmt.Fprintln(c.output, "Clearing saved session...")
 		_ = c.sessionStore.Delete()
 		return nil
 	}
@@ -234,7 +241,7 @@ func (c *Client) upgradeSessionIfNeeded(session *api.Session) error {
 		return nil
 	}
 
-	fmt.Println("Session lacks VPN scope - 2FA verification required to upgrade session...")
+	fmt.Fprintln(c.output, "Session lacks VPN scope - 2FA verification required to upgrade session...")
 	code, err := c.get2FACode()
 	if err != nil {
 		return fmt.Errorf("failed to get 2FA code: %w", err)
@@ -245,7 +252,7 @@ func (c *Client) upgradeSessionIfNeeded(session *api.Session) error {
 		return fmt.Errorf("2FA verification failed: %w", err)
 	}
 	session.Scopes = updatedScopes
-	fmt.Println("2FA verified - session upgraded with VPN scope")
+	fmt.Fprintln(c.output, "2FA verified - session upgraded with VPN scope")
 	return nil
 }
 
@@ -270,18 +277,18 @@ func (c *Client) saveSessionIfEnabled(session *api.Session) {
 
 	sessionDuration, err := timeutil.ParseSessionDuration(c.config.SessionDuration)
 	if err != nil {
-		fmt.Printf("Warning: Invalid session duration, using default: %v\n", err)
+		fmt.Fprintf(c.output, "Warning: Invalid session duration, using default: %v\n", err)
 		sessionDuration = 0
 	}
 
 	if err := c.sessionStore.Save(session, c.config.Username, sessionDuration); err != nil {
-		fmt.Printf("Warning: Failed to save session: %v\n", err)
+		fmt.Fprintf(c.output, "Warning: Failed to save session: %v\n", err)
 	}
 }
 
 func (c *Client) ensureUsername() error {
 	if c.config.Username == "" {
-		fmt.Print("Username (without @protonmail.com): ")
+		fmt.Fprint(c.output, "Username (without @protonmail.com): ")
 		reader := bufio.NewReader(os.Stdin)
 		username, err := reader.ReadString('\n')
 		if err != nil {
@@ -297,9 +304,9 @@ func (c *Client) ensureUsername() error {
 
 func (c *Client) ensurePassword() error {
 	if c.config.Password == "" {
-		fmt.Print("Password: ")
+		fmt.Fprint(c.output, "Password: ")
 		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-		fmt.Println()
+		fmt.Fprintln(c.output)
 		if err != nil {
 			return fmt.Errorf("error reading password: %w", err)
 		}
@@ -309,7 +316,7 @@ func (c *Client) ensurePassword() error {
 }
 
 func (c *Client) get2FACode() (string, error) {
-	fmt.Print("2FA Code: ")
+	fmt.Fprint(c.output, "2FA Code: ")
 	reader := bufio.NewReader(os.Stdin)
 	code, err := reader.ReadString('\n')
 	if err != nil {
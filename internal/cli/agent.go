@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/localagent"
+	"protonvpn-wg-confgen/pkg/validation"
+)
+
+// agentUsage describes the agent subcommand
+const agentUsage = `usage: agent <action> [options]
+
+Actions:
+  status   Show the connection status reported by the gateway
+  set      Change features of the running connection (e.g., -netshield 2 -port-forwarding)`
+
+// agentOptions holds the flags of the agent subcommand
+type agentOptions struct {
+	Username       string
+	KeyProfile     string
+	KeyStoreDir    string
+	Address        string
+	ServerName     string
+	CAFile         string
+	Timeout        time.Duration
+	Stats          bool
+	JSON           bool
+	NetShieldLevel int
+	ModerateNAT    optionalBool
+	PortForwarding optionalBool
+	SplitTCP       optionalBool
+}
+
+func (o *agentOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Username, "username", "", "User of the stored key (required when several users have one)")
+	fs.StringVar(&o.KeyProfile, "key-profile", constants.DefaultKeyProfile, "Key store profile of the running configuration")
+	fs.StringVar(&o.KeyStoreDir, "key-store-dir", "", "Directory for persisted device keys (default: ~/.protonvpn-keys)")
+	fs.StringVar(&o.Address, "address", localagent.DefaultAddress, "Local agent address inside the tunnel")
+	fs.StringVar(&o.ServerName, "server-name", "", "TLS server name of the gateway (default: the stored server's domain)")
+	fs.StringVar(&o.CAFile, "ca-file", "", "PEM file with the CA to verify the gateway certificate (default: trust the tunnel)")
+	fs.DurationVar(&o.Timeout, "timeout", localagent.DefaultTimeout, "Timeout for connecting and for each reply")
+	fs.BoolVar(&o.Stats, "stats", false, "status: include NetShield statistics")
+	fs.BoolVar(&o.JSON, "json", false, "Print machine-readable JSON")
+	fs.IntVar(&o.NetShieldLevel, "netshield", -1, "set: NetShield level (0=off, 1=malware, 2=ads+malware)")
+	fs.Var(&o.ModerateNAT, "moderate-nat", "set: enable or disable moderate NAT")
+	fs.Var(&o.PortForwarding, "port-forwarding", "set: enable or disable port forwarding")
+	fs.Var(&o.SplitTCP, "split-tcp", "set: enable or disable VPN Accelerator")
+}
+
+// features returns the features selected with the set flags
+func (o *agentOptions) features() (localagent.Features, error) {
+	var features localagent.Features
+	if o.NetShieldLevel != -1 {
+		if o.NetShieldLevel < constants.NetShieldOff || o.NetShieldLevel > constants.NetShieldAdsMalware {
+			return features, fmt.Errorf("invalid -netshield value: %d (expected 0, 1 or 2)", o.NetShieldLevel)
+		}
+		level := o.NetShieldLevel
+		features.NetShieldLevel = &level
+	}
+	if o.ModerateNAT.set {
+		// Moderate NAT is the absence of randomized NAT
+		randomized := !o.ModerateNAT.value
+		features.RandomizedNAT = &randomized
+	}
+	features.PortForwarding = o.PortForwarding.pointer()
+	features.SplitTCP = o.SplitTCP.pointer()
+	return features, nil
+}
+
+// optionalBool is a boolean flag that records whether it was given
+type optionalBool struct {
+	set   bool
+	value bool
+}
+
+func (b *optionalBool) String() string {
+	if !b.set {
+		return ""
+	}
+	return strconv.FormatBool(b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.set, b.value = true, value
+	return nil
+}
+
+func (b *optionalBool) IsBoolFlag() bool {
+	return true
+}
+
+func (b *optionalBool) pointer() *bool {
+	if !b.set {
+		return nil
+	}
+	value := b.value
+	return &value
+}
+
+// RunAgent implements the agent subcommand. It connects to the local agent of the
+// gateway with the stored certificate and key, so the tunnel must be up.
+func RunAgent(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", agentUsage)
+	}
+
+	action := args[0]
+	var opts agentOptions
+	fs := flag.NewFlagSet("agent "+action, flag.ContinueOnError)
+	opts.register(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var features localagent.Features
+	switch action {
+	case "status":
+	case "set":
+		var err error
+		if features, err = opts.features(); err != nil {
+			return err
+		}
+		if features.IsEmpty() {
+			return fmt.Errorf("agent set requires at least one of -netshield, -moderate-nat, -port-forwarding or -split-tcp")
+		}
+	default:
+		return fmt.Errorf("unknown agent action: %s\n%s", action, agentUsage)
+	}
+
+	store := keystore.NewStore(opts.KeyStoreDir, os.Getenv(constants.KeyPassphraseEnv))
+	agentConfig, err := newAgentConfig(store, &opts)
+	if err != nil {
+		return err
+	}
+
+	client, err := localagent.Dial(opts.Address, agentConfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	var status *localagent.Status
+	if action == "set" {
+		status, err = client.SetFeatures(features)
+	} else {
+		status, err = client.Status(opts.Stats)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.JSON {
+		return printJSON(status)
+	}
+	printAgentStatus(status)
+	return nil
+}
+
+// newAgentConfig builds the TLS settings from the stored certificate and key
+func newAgentConfig(store *keystore.Store, opts *agentOptions) (*localagent.Config, error) {
+	entry, err := findAgentEntry(store, validation.CleanUsername(opts.Username), opts.KeyProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	_, keyPair, err := store.Load(entry.Username, entry.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Certificate.Certificate == "" {
+		return nil, fmt.Errorf("no certificate PEM stored for profile %s; regenerate the config to record it", entry.Profile)
+	}
+	if !time.Now().Before(entry.Certificate.ExpiresAt()) {
+		fmt.Fprintf(messageOutput(opts.JSON), "Warning: The stored certificate for profile %s has expired; the gateway will reject it\n", entry.Profile)
+	}
+
+	cert, err := localagent.NewCertificate(entry.Certificate.Certificate, keyPair.PrivateKey())
+	if err != nil {
+		return nil, fmt.Errorf("stored certificate for profile %s: %w", entry.Profile, err)
+	}
+
+	agentConfig := &localagent.Config{
+		Certificate: cert,
+		ServerName:  opts.ServerName,
+		Timeout:     opts.Timeout,
+	}
+	if agentConfig.ServerName == "" {
+		agentConfig.ServerName = entry.Server.Domain
+	}
+
+	if opts.CAFile != "" {
+		data, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		agentConfig.RootCAs = x509.NewCertPool()
+		if !agentConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", opts.CAFile)
+		}
+		if agentConfig.ServerName == "" {
+			return nil, fmt.Errorf("-ca-file requires -server-name: no server domain is stored for profile %s", entry.Profile)
+		}
+	}
+
+	return agentConfig, nil
+}
+
+// findAgentEntry returns the stored entry for the profile, which must be unambiguous without -username
+func findAgentEntry(store *keystore.Store, username, profile string) (*keystore.Entry, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []keystore.Entry
+	for _, entry := range entries {
+		if entry.Profile == profile && (username == "" || entry.Username == username) {
+			matches = append(matches, entry)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no stored key found for profile %s", profile)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("profile %s is stored for %d users; select one with -username", profile, len(matches))
+	}
+}
+
+// printAgentStatus prints the agent status as text
+func printAgentStatus(status *localagent.Status) {
+	fmt.Printf("State:       %s\n", status.State)
+	if status.Reason != nil {
+		description := status.Reason.Description
+		if description == "" {
+			description = localagent.DescribeCode(status.Reason.Code)
+		}
+		fmt.Printf("Reason:      %s (code %d)\n", description, status.Reason.Code)
+	}
+	if status.SwitchTo != "" {
+		fmt.Printf("Switch to:   %s\n", status.SwitchTo)
+	}
+	if details := status.ConnectionDetails; details != nil {
+		fmt.Printf("Device IP:   %s (%s)\n", details.DeviceIP, details.DeviceCountry)
+		fmt.Printf("Server IP:   %s\n", strings.Trim(details.ServerIPv4+" "+details.ServerIPv6, " "))
+	}
+
+	fmt.Printf("Features:    %s\n", describeAgentFeatures(&status.Features))
+	if len(status.Restrictions) > 0 {
+		fmt.Printf("Restricted:  %s\n", strings.Join(status.Restrictions, ", "))
+	}
+	if len(status.FeaturesStatistics) > 0 {
+		keys := make([]string, 0, len(status.FeaturesStatistics))
+		for key := range status.FeaturesStatistics {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("Statistics:  %s: %v\n", key, status.FeaturesStatistics[key])
+		}
+	}
+}
+
+// describeAgentFeatures lists the features reported by the agent
+func describeAgentFeatures(features *localagent.Features) string {
+	var parts []string
+	if features.NetShieldLevel != nil {
+		parts = append(parts, fmt.Sprintf("NetShield %d", *features.NetShieldLevel))
+	}
+	if features.RandomizedNAT != nil {
+		parts = append(parts, "Moderate NAT "+onOff(!*features.RandomizedNAT))
+	}
+	if features.PortForwarding != nil {
+		parts = append(parts, "Port Forwarding "+onOff(*features.PortForwarding))
+	}
+	if features.SplitTCP != nil {
+		parts = append(parts, "VPN Accelerator "+onOff(*features.SplitTCP))
+	}
+	if features.Bouncing != nil {
+		parts = append(parts, "Bouncing "+*features.Bouncing)
+	}
+	if len(parts) == 0 {
+		return "none reported"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"flag"
+	"testing"
+
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/keys"
+)
+
+func TestAgentFeatures(t *testing.T) {
+	var opts agentOptions
+	fs := flag.NewFlagSet("agent set", flag.ContinueOnError)
+	opts.register(fs)
+	if err := fs.Parse([]string{"-netshield", "2", "-moderate-nat", "-port-forwarding=false"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	features, err := opts.features()
+	if err != nil {
+		t.Fatalf("features failed: %v", err)
+	}
+	if features.NetShieldLevel == nil || *features.NetShieldLevel != 2 {
+		t.Errorf("Expected NetShield level 2, got %v", features.NetShieldLevel)
+	}
+	if features.RandomizedNAT == nil || *features.RandomizedNAT {
+		t.Error("Expected -moderate-nat to disable randomized NAT")
+	}
+	if features.PortForwarding == nil || *features.PortForwarding {
+		t.Error("Expected -port-forwarding=false to disable port forwarding")
+	}
+	if features.SplitTCP != nil {
+		t.Error("Expected -split-tcp to stay unchanged")
+	}
+
+	opts.NetShieldLevel = 3
+	if _, err := opts.features(); err == nil {
+		t.Error("Expected an invalid NetShield level to be rejected")
+	}
+}
+
+func TestFindAgentEntry(t *testing.T) {
+	store := keystore.NewStore(t.TempDir(), "")
+	keyPair, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, username := range []string{"alice", "bob"} {
+		if err := store.Save(&keystore.Entry{Profile: "default", Username: username}, keyPair); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	if _, err := findAgentEntry(store, "", "default"); err == nil {
+		t.Error("Expected an ambiguous profile to be rejected without -username")
+	}
+	entry, err := findAgentEntry(store, "bob", "default")
+	if err != nil || entry.Username != "bob" {
+		t.Errorf("Expected bob's entry, got %v, %v", entry, err)
+	}
+	if _, err := findAgentEntry(store, "alice", "router"); err == nil {
+		t.Error("Expected a missing profile to be rejected")
+	}
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"protonvpn-wg-confgen/internal/auth"
+	"protonvpn-wg-confgen/internal/config"
+	"protonvpn-wg-confgen/internal/vpn"
+)
+
+// newVPNClient authenticates and returns a VPN API client.
+// With quiet set, authentication progress goes to stderr so stdout stays machine-readable.
+func newVPNClient(cfg *config.Config, quiet bool) (*vpn.Client, error) {
+	authClient := auth.NewClient(cfg)
+	authClient.SetOutput(messageOutput(quiet))
+
+	session, err := authClient.Authenticate()
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	return vpn.NewClient(cfg, session), nil
+}
+
+// messageOutput returns the writer for progress messages and warnings:
+// stderr when stdout is reserved for machine-readable output, stdout otherwise
+func messageOutput(quiet bool) io.Writer {
+	if quiet {
+		return os.Stderr
+	}
+	return os.Stdout
+}
@@ -0,0 +1,310 @@
+// Package cli implements the subcommands of the command-line interface.
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/config"
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/internal/vpn"
+	"protonvpn-wg-confgen/pkg/timeutil"
+)
+
+// devicesUsage describes the devices subcommand
+const devicesUsage = `usage: devices <action> [options] [arguments]
+
+Actions:
+  list                          List persistent devices
+  show <name|serial>            Show details of one device
+  revoke <name|serial>          Revoke (delete) one device
+  prune -prefix P -older-than D Revoke all devices matching a name prefix and/or older than a duration`
+
+// devicesOptions holds the flags of the devices subcommand
+type devicesOptions struct {
+	JSON        bool
+	Prefix      string
+	OlderThan   string
+	DryRun      bool
+	KeyStoreDir string
+}
+
+func (o *devicesOptions) register(fs *flag.FlagSet, _ *config.Config) {
+	fs.BoolVar(&o.JSON, "json", false, "Print machine-readable JSON")
+	fs.StringVar(&o.Prefix, "prefix", "", "prune: only devices whose name starts with this prefix")
+	fs.StringVar(&o.OlderThan, "older-than", "", "prune: only devices created longer ago than this duration (e.g., 30d)")
+	fs.BoolVar(&o.DryRun, "dry-run", false, "prune: only print the devices that would be revoked")
+	fs.StringVar(&o.KeyStoreDir, "key-store-dir", "", "Directory for persisted device keys (default: ~/.protonvpn-keys)")
+}
+
+// deviceView is the JSON and text representation of a persistent device
+type deviceView struct {
+	DeviceName   string     `json:"device_name"`
+	SerialNumber string     `json:"serial_number"`
+	Fingerprint  string     `json:"client_key_fingerprint"`
+	Mode         string     `json:"mode"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	RefreshAt    time.Time  `json:"refresh_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	Features     []string   `json:"features"`
+}
+
+func newDeviceView(cert *api.VPNInfo) deviceView {
+	view := deviceView{
+		DeviceName:   cert.DeviceName,
+		SerialNumber: cert.SerialNumber,
+		Fingerprint:  cert.ClientKeyFingerprint,
+		Mode:         cert.Mode,
+		RefreshAt:    time.Unix(cert.RefreshTime, 0),
+		ExpiresAt:    time.Unix(cert.ExpirationTime, 0),
+		Features:     api.GetCertificateFeatureNames(cert.Features),
+	}
+	if created := vpn.CertificateCreatedAt(cert); !created.IsZero() {
+		view.CreatedAt = &created
+	}
+	if view.Features == nil {
+		view.Features = []string{}
+	}
+	return view
+}
+
+// RunDevices implements the devices subcommand
+func RunDevices(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", devicesUsage)
+	}
+
+	action := args[0]
+	var opts devicesOptions
+	cfg, rest, err := config.ParseCommand("devices "+action, args[1:], opts.register)
+	if err != nil {
+		return err
+	}
+
+	var olderThan time.Duration
+	switch action {
+	case "list":
+	case "show", "revoke":
+		if len(rest) != 1 {
+			return fmt.Errorf("devices %s requires exactly one device name or serial number", action)
+		}
+	case "prune":
+		if opts.Prefix == "" && opts.OlderThan == "" {
+			return fmt.Errorf("devices prune requires -prefix and/or -older-than")
+		}
+		if opts.OlderThan != "" {
+			if olderThan, err = timeutil.ParseDuration(opts.OlderThan); err != nil {
+				return fmt.Errorf("invalid -older-than value: %s", opts.OlderThan)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown devices action: %s\n%s", action, devicesUsage)
+	}
+
+	vpnClient, err := newVPNClient(cfg, opts.JSON)
+	if err != nil {
+		return err
+	}
+
+	store := keystore.NewStore(opts.KeyStoreDir, os.Getenv(constants.KeyPassphraseEnv))
+
+	certificates, err := vpnClient.ListCertificates()
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	switch action {
+	case "list":
+		return printDevices(certificates, opts.JSON)
+	case "show":
+		cert, err := findDevice(certificates, rest[0])
+		if err != nil {
+			return err
+		}
+		return printDevice(cert, opts.JSON)
+	case "revoke":
+		cert, err := findDevice(certificates, rest[0])
+		if err != nil {
+			return err
+		}
+		return revokeDevices(vpnClient, store, []api.VPNInfo{*cert}, false, opts.JSON)
+	default:
+		matches := filterDevices(certificates, opts.Prefix, olderThan, time.Now())
+		return revokeDevices(vpnClient, store, matches, opts.DryRun, opts.JSON)
+	}
+}
+
+// findDevice finds a device by serial number, key fingerprint or unique name
+func findDevice(certificates []api.VPNInfo, target string) (*api.VPNInfo, error) {
+	var byName []*api.VPNInfo
+	for i := range certificates {
+		cert := &certificates[i]
+		if cert.SerialNumber == target || cert.ClientKeyFingerprint == target {
+			return cert, nil
+		}
+		if cert.DeviceName == target {
+			byName = append(byName, cert)
+		}
+	}
+
+	switch len(byName) {
+	case 0:
+		return nil, fmt.Errorf("device not found: %s", target)
+	case 1:
+		return byName[0], nil
+	default:
+		return nil, fmt.Errorf("%d devices are named %s; use the serial number instead", len(byName), target)
+	}
+}
+
+// filterDevices returns the devices matching the prune criteria.
+// Devices with an unknown creation time never match an age criterion.
+func filterDevices(certificates []api.VPNInfo, prefix string, olderThan time.Duration, now time.Time) []api.VPNInfo {
+	var matches []api.VPNInfo
+	for i := range certificates {
+		cert := &certificates[i]
+		if prefix != "" && !strings.HasPrefix(cert.DeviceName, prefix) {
+			continue
+		}
+		if olderThan > 0 {
+			created := vpn.CertificateCreatedAt(cert)
+			if created.IsZero() || now.Sub(created) < olderThan {
+				continue
+			}
+		}
+		matches = append(matches, *cert)
+	}
+	return matches
+}
+
+// revokeDevices deletes the given devices and their stored keys, or only reports them in dry-run mode
+func revokeDevices(vpnClient *vpn.Client, store *keystore.Store, certificates []api.VPNInfo, dryRun, asJSON bool) error {
+	var forgotten []keystore.Entry
+	if len(certificates) > 0 && !dryRun {
+		fingerprints := make([]string, 0, len(certificates))
+		for i := range certificates {
+			fingerprints = append(fingerprints, certificates[i].ClientKeyFingerprint)
+		}
+		if err := vpnClient.DeleteCertificates(fingerprints); err != nil {
+			return fmt.Errorf("failed to revoke devices: %w", err)
+		}
+
+		var err error
+		if forgotten, err = forgetRevokedKeys(store, fingerprints); err != nil {
+			return fmt.Errorf("devices were revoked but their stored keys could not be removed: %w", err)
+		}
+	}
+
+	if asJSON {
+		return printDevices(certificates, true)
+	}
+
+	verb := "Revoked"
+	if dryRun {
+		verb = "Would revoke"
+	}
+	for i := range certificates {
+		fmt.Printf("%s: %s (serial %s)\n", verb, certificates[i].DeviceName, certificates[i].SerialNumber)
+	}
+	fmt.Printf("%s %d device(s)\n", verb, len(certificates))
+	for i := range forgotten {
+		fmt.Printf("Removed stored key: profile %s (user %s)\n", forgotten[i].Profile, forgotten[i].Username)
+	}
+	return nil
+}
+
+// forgetRevokedKeys deletes the key store entries whose key has one of the revoked fingerprints,
+// so the next run registers a new key instead of reusing one the API no longer accepts.
+// Entries saved without a fingerprint are matched by their decoded key when possible.
+func forgetRevokedKeys(store *keystore.Store, fingerprints []string) ([]keystore.Entry, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var forgotten []keystore.Entry
+	for i := range entries {
+		entry := &entries[i]
+		fingerprint := entry.Certificate.Fingerprint
+		if fingerprint == "" {
+			if _, keyPair, err := store.Load(entry.Username, entry.Profile); err == nil && keyPair != nil {
+				fingerprint = keyPair.Fingerprint()
+			}
+		}
+		if fingerprint == "" || !slices.Contains(fingerprints, fingerprint) {
+			continue
+		}
+
+		if err := store.Delete(entry.Username, entry.Profile); err != nil {
+			return forgotten, err
+		}
+		forgotten = append(forgotten, *entry)
+	}
+	return forgotten, nil
+}
+
+// printDevices prints devices as a table or JSON array
+func printDevices(certificates []api.VPNInfo, asJSON bool) error {
+	views := make([]deviceView, 0, len(certificates))
+	for i := range certificates {
+		views = append(views, newDeviceView(&certificates[i]))
+	}
+
+	if asJSON {
+		return printJSON(views)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSERIAL\tCREATED\tEXPIRES")
+	for i := range views {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", views[i].DeviceName, views[i].SerialNumber,
+			formatTime(views[i].CreatedAt), views[i].ExpiresAt.Format("2006-01-02"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nTotal devices: %d\n", len(views))
+	return nil
+}
+
+// printDevice prints details of one device
+func printDevice(cert *api.VPNInfo, asJSON bool) error {
+	view := newDeviceView(cert)
+	if asJSON {
+		return printJSON(view)
+	}
+
+	fmt.Printf("Name:        %s\n", view.DeviceName)
+	fmt.Printf("Serial:      %s\n", view.SerialNumber)
+	fmt.Printf("Fingerprint: %s\n", view.Fingerprint)
+	fmt.Printf("Mode:        %s\n", view.Mode)
+	fmt.Printf("Created:     %s\n", formatTime(view.CreatedAt))
+	fmt.Printf("Refresh:     %s\n", view.RefreshAt.Format(time.RFC3339))
+	fmt.Printf("Expires:     %s (in %s)\n", view.ExpiresAt.Format(time.RFC3339), timeutil.HumanizeDuration(time.Until(view.ExpiresAt)))
+	if len(view.Features) > 0 {
+		fmt.Printf("Features:    %s\n", strings.Join(view.Features, ", "))
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "unknown"
+	}
+	return t.Format("2006-01-02")
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
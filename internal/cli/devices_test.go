@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/keys"
+)
+
+func testCertificates(now time.Time) []api.VPNInfo {
+	return []api.VPNInfo{
+		{DeviceName: "WireGuard-alice-" + strconv.FormatInt(now.Add(-60*24*time.Hour).Unix(), 10), SerialNumber: "1", ClientKeyFingerprint: "fp1"},
+		{DeviceName: "WireGuard-alice-" + strconv.FormatInt(now.Add(-2*24*time.Hour).Unix(), 10), SerialNumber: "2", ClientKeyFingerprint: "fp2"},
+		{DeviceName: "router", SerialNumber: "3", ClientKeyFingerprint: "fp3", CreationTime: now.Add(-90 * 24 * time.Hour).Unix()},
+		{DeviceName: "laptop", SerialNumber: "4", ClientKeyFingerprint: "fp4"},
+		{DeviceName: "laptop", SerialNumber: "5", ClientKeyFingerprint: "fp5"},
+	}
+}
+
+func TestFilterDevices(t *testing.T) {
+	now := time.Now()
+	certs := testCertificates(now)
+
+	tests := []struct {
+		name      string
+		prefix    string
+		olderThan time.Duration
+		want      []string
+	}{
+		{"prefix", "WireGuard-", 0, []string{"1", "2"}},
+		{"older than", "", 30 * 24 * time.Hour, []string{"1", "3"}},
+		{"both", "WireGuard-", 30 * 24 * time.Hour, []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		matches := filterDevices(certs, tt.prefix, tt.olderThan, now)
+		if len(matches) != len(tt.want) {
+			t.Errorf("%s: expected %d matches, got %d", tt.name, len(tt.want), len(matches))
+			continue
+		}
+		for i := range matches {
+			if matches[i].SerialNumber != tt.want[i] {
+				t.Errorf("%s: expected serial %s, got %s", tt.name, tt.want[i], matches[i].SerialNumber)
+			}
+		}
+	}
+}
+
+func TestFindDevice(t *testing.T) {
+	certs := testCertificates(time.Now())
+
+	if cert, err := findDevice(certs, "router"); err != nil || cert.SerialNumber != "3" {
+		t.Errorf("Expected to find router by name, got %v, %v", cert, err)
+	}
+	if cert, err := findDevice(certs, "5"); err != nil || cert.DeviceName != "laptop" {
+		t.Errorf("Expected to find laptop by serial, got %v, %v", cert, err)
+	}
+	if _, err := findDevice(certs, "laptop"); err == nil {
+		t.Error("Expected error for ambiguous device name")
+	}
+	if _, err := findDevice(certs, "missing"); err == nil {
+		t.Error("Expected error for unknown device")
+	}
+}
+
+func TestForgetRevokedKeys(t *testing.T) {
+	store := keystore.NewStore(t.TempDir(), "")
+	revoked, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	kept, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	entries := []struct {
+		profile     string
+		keyPair     *keys.KeyPair
+		fingerprint string
+	}{
+		{"router", revoked, revoked.Fingerprint()},
+		{"legacy", revoked, ""}, // Saved before fingerprints were recorded
+		{"laptop", kept, kept.Fingerprint()},
+	}
+	for _, e := range entries {
+		entry := &keystore.Entry{Profile: e.profile, Username: "alice"}
+		entry.Certificate.Fingerprint = e.fingerprint
+		if err := store.Save(entry, e.keyPair); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	forgotten, err := forgetRevokedKeys(store, []string{revoked.Fingerprint()})
+	if err != nil {
+		t.Fatalf("forgetRevokedKeys failed: %v", err)
+	}
+	if len(forgotten) != 2 {
+		t.Errorf("Expected 2 removed entries, got %d", len(forgotten))
+	}
+
+	remaining, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Profile != "laptop" {
+		t.Errorf("Expected only the laptop entry to remain, got %v", remaining)
+	}
+}
@@ -0,0 +1,11 @@
+package cli
+
+// ExitError is returned by subcommands that report their result through a specific exit code
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
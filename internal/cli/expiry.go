@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/timeutil"
+	"protonvpn-wg-confgen/pkg/validation"
+	"protonvpn-wg-confgen/pkg/wireguard"
+)
+
+// expiryOptions holds the flags of the check-expiry subcommand
+type expiryOptions struct {
+	Threshold   string
+	Username    string
+	KeyStoreDir string
+	Webhook     string
+	Exec        string
+	WarnOnly    bool
+	JSON        bool
+}
+
+func (o *expiryOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Threshold, "threshold", constants.DefaultExpiryThreshold, "Report certificates expiring within this duration (e.g., 14d, 720h)")
+	fs.StringVar(&o.Username, "username", "", "Only check stored certificates of this user")
+	fs.StringVar(&o.KeyStoreDir, "key-store-dir", "", "Directory for persisted device keys (default: ~/.protonvpn-keys)")
+	fs.StringVar(&o.Webhook, "webhook", "", "POST a JSON notification to this URL when certificates are expiring")
+	fs.StringVar(&o.Exec, "exec", "", "Shell command to run when certificates are expiring ($PROTONVPN_EXPIRY_MESSAGE)")
+	fs.BoolVar(&o.WarnOnly, "warn-only", false, "Only warn; exit with 0 even when certificates are expiring")
+	fs.BoolVar(&o.JSON, "json", false, "Print machine-readable JSON")
+}
+
+// Expiry check statuses
+const (
+	expiryOK       = "ok"
+	expiryExpiring = "expiring"
+	expiryExpired  = "expired"
+	expiryUnknown  = "unknown"
+)
+
+// expiryResult is the expiry status of one config or stored certificate
+type expiryResult struct {
+	Source    string     `json:"source"`
+	Device    string     `json:"device,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Status    string     `json:"status"`
+	Message   string     `json:"message"`
+}
+
+// RunCheckExpiry implements the check-expiry subcommand.
+// It checks the given configs, or all stored certificates when none are given.
+func RunCheckExpiry(args []string) error {
+	var opts expiryOptions
+	fs := flag.NewFlagSet("check-expiry", flag.ContinueOnError)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	threshold, err := timeutil.ParseDuration(opts.Threshold)
+	if err != nil {
+		return fmt.Errorf("invalid -threshold value: %s", opts.Threshold)
+	}
+
+	store := keystore.NewStore(opts.KeyStoreDir, os.Getenv(constants.KeyPassphraseEnv))
+	now := time.Now()
+
+	var results []expiryResult
+	if fs.NArg() > 0 {
+		for _, path := range fs.Args() {
+			results = append(results, checkConfigExpiry(store, path, now, threshold))
+		}
+	} else {
+		results, err = checkStoredExpiry(store, validation.CleanUsername(opts.Username), now, threshold)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return fmt.Errorf("no stored certificates found; pass config files to check instead")
+		}
+	}
+
+	if opts.JSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printExpiryResults(results)
+	}
+
+	expiring := expiringResults(results)
+	if len(expiring) == 0 {
+		return nil
+	}
+
+	message := expiryMessage(expiring, threshold)
+	if opts.Webhook != "" {
+		if err := sendExpiryWebhook(opts.Webhook, message, expiring); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to send expiry webhook: %v\n", err)
+		}
+	}
+	if opts.Exec != "" {
+		if err := runExpiryCommand(opts.Exec, message, len(expiring)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Expiry command failed: %v\n", err)
+		}
+	}
+
+	if opts.WarnOnly {
+		return nil
+	}
+	return &ExitError{Code: constants.ExitCodeExpiring, Message: message}
+}
+
+// checkConfigExpiry checks a config using the stored certificate of its key, falling back to its metadata
+// header. The header is a snapshot: -renew re-registers the key without rewriting an unchanged config.
+func checkConfigExpiry(store *keystore.Store, path string, now time.Time, threshold time.Duration) expiryResult {
+	parsed, err := wireguard.ParseConfigFile(path)
+	if err != nil {
+		return expiryResult{Source: path, Status: expiryUnknown, Message: err.Error()}
+	}
+
+	expiresAt := parsed.CertificateExpires
+	var view inspectView
+	if entry, _, err := findStoredKey(store, &inspectOptions{}, parsed.PrivateKey, &view); err == nil && entry.Certificate.ExpirationTime > 0 {
+		expiresAt = entry.Certificate.ExpiresAt()
+	}
+	if expiresAt.IsZero() {
+		return expiryResult{Source: path, Device: parsed.Device, Status: expiryUnknown,
+			Message: "expiry unknown (no expiry in the config header and no matching stored key)"}
+	}
+
+	return newExpiryResult(path, parsed.Device, expiresAt, now, threshold)
+}
+
+// checkStoredExpiry checks all stored certificates, optionally restricted to one user
+func checkStoredExpiry(store *keystore.Store, username string, now time.Time, threshold time.Duration) ([]expiryResult, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []expiryResult
+	for i := range entries {
+		entry := &entries[i]
+		if username != "" && entry.Username != username {
+			continue
+		}
+		source := entry.Username + "/" + entry.Profile
+		if entry.Certificate.ExpirationTime == 0 {
+			results = append(results, expiryResult{Source: source, Device: entry.Certificate.DeviceName,
+				Status: expiryUnknown, Message: "no certificate recorded"})
+			continue
+		}
+		results = append(results, newExpiryResult(source, entry.Certificate.DeviceName, entry.Certificate.ExpiresAt(), now, threshold))
+	}
+	return results, nil
+}
+
+// newExpiryResult classifies an expiration time against the threshold
+func newExpiryResult(source, device string, expiresAt, now time.Time, threshold time.Duration) expiryResult {
+	result := expiryResult{Source: source, Device: device, ExpiresAt: &expiresAt, Message: describeExpiry(expiresAt, now)}
+
+	switch remaining := expiresAt.Sub(now); {
+	case remaining <= 0:
+		result.Status = expiryExpired
+	case remaining <= threshold:
+		result.Status = expiryExpiring
+	default:
+		result.Status = expiryOK
+	}
+	return result
+}
+
+// expiringResults returns the results that are expiring or expired
+func expiringResults(results []expiryResult) []expiryResult {
+	var expiring []expiryResult
+	for _, result := range results {
+		if result.Status == expiryExpiring || result.Status == expiryExpired {
+			expiring = append(expiring, result)
+		}
+	}
+	return expiring
+}
+
+// expiryMessage summarizes the expiring certificates in one notification text
+func expiryMessage(expiring []expiryResult, threshold time.Duration) string {
+	lines := make([]string, 0, len(expiring)+1)
+	lines = append(lines, fmt.Sprintf("%d ProtonVPN WireGuard certificate(s) expire within %s:",
+		len(expiring), timeutil.HumanizeDuration(threshold)))
+	for _, result := range expiring {
+		lines = append(lines, fmt.Sprintf("- %s: %s", expiryLabel(&result), result.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func expiryLabel(result *expiryResult) string {
+	if result.Device != "" {
+		return fmt.Sprintf("%s (%s)", result.Source, result.Device)
+	}
+	return result.Source
+}
+
+// printExpiryResults prints one line per checked certificate
+func printExpiryResults(results []expiryResult) {
+	for i := range results {
+		result := &results[i]
+		switch result.Status {
+		case expiryOK:
+			fmt.Printf("OK: %s: %s\n", expiryLabel(result), result.Message)
+		case expiryUnknown:
+			fmt.Printf("Warning: %s: %s\n", expiryLabel(result), result.Message)
+		default:
+			fmt.Printf("%s: %s: %s\n", strings.ToUpper(result.Status), expiryLabel(result), result.Message)
+		}
+	}
+}
+
+// sendExpiryWebhook posts the notification as JSON; the text field works with Slack-compatible webhooks
+func sendExpiryWebhook(url, message string, expiring []expiryResult) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"text":         message,
+		"certificates": expiring,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runExpiryCommand runs the -exec command through the shell with the notification in the environment
+func runExpiryCommand(command, message string, count int) error {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"PROTONVPN_EXPIRY_MESSAGE="+message,
+		"PROTONVPN_EXPIRING_COUNT="+strconv.Itoa(count),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/keys"
+)
+
+func TestNewExpiryResult(t *testing.T) {
+	now := time.Now()
+	threshold := 30 * 24 * time.Hour
+
+	tests := []struct {
+		expiresAt time.Time
+		want      string
+	}{
+		{now.Add(60 * 24 * time.Hour), expiryOK},
+		{now.Add(10 * 24 * time.Hour), expiryExpiring},
+		{now.Add(-time.Hour), expiryExpired},
+	}
+
+	for _, tt := range tests {
+		if got := newExpiryResult("test", "", tt.expiresAt, now, threshold); got.Status != tt.want {
+			t.Errorf("Expected status %s for expiry %v, got %s (%s)", tt.want, tt.expiresAt, got.Status, got.Message)
+		}
+	}
+}
+
+func TestCheckExpiryWebhook(t *testing.T) {
+	var payload struct {
+		Text         string         `json:"text"`
+		Certificates []expiryResult `json:"certificates"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Invalid webhook payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	expiresAt := time.Now().Add(5 * 24 * time.Hour).UTC().Format("2006-01-02 15:04:05 MST")
+	config := "# Device: router\n# Certificate Expires: " + expiresAt + "\n\n[Interface]\nPrivateKey = key=\n"
+	path := filepath.Join(dir, "router.conf")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	err := RunCheckExpiry([]string{"-key-store-dir", dir, "-threshold", "14d", "-webhook", server.URL, path})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != constants.ExitCodeExpiring {
+		t.Fatalf("Expected expiring exit error, got %v", err)
+	}
+	if len(payload.Certificates) != 1 || payload.Certificates[0].Device != "router" || !strings.Contains(payload.Text, "router") {
+		t.Errorf("Unexpected webhook payload: %+v", payload)
+	}
+
+	if err := RunCheckExpiry([]string{"-key-store-dir", dir, "-threshold", "1d", path}); err != nil {
+		t.Errorf("Expected no error outside the threshold, got %v", err)
+	}
+}
+
+func TestCheckConfigExpiryPrefersStoredCertificate(t *testing.T) {
+	dir := t.TempDir()
+	store := keystore.NewStore(dir, "")
+	keyPair, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// -renew re-registered the key, but the unchanged config keeps the old expiry in its header
+	now := time.Now()
+	renewed := now.Add(365 * 24 * time.Hour)
+	entry := &keystore.Entry{Profile: "router", Username: "alice"}
+	entry.Certificate.ExpirationTime = renewed.Unix()
+	if err := store.Save(entry, keyPair); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	header := now.Add(2 * 24 * time.Hour).UTC().Format("2006-01-02 15:04:05 MST")
+	config := "# Device: router\n# Certificate Expires: " + header + "\n\n[Interface]\nPrivateKey = " + keyPair.ToX25519Base64() + "\n"
+	path := filepath.Join(dir, "router.conf")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	result := checkConfigExpiry(store, path, now, 14*24*time.Hour)
+	if result.Status != expiryOK || result.ExpiresAt == nil || result.ExpiresAt.Unix() != renewed.Unix() {
+		t.Errorf("Expected the stored expiry %v to win over the header, got %s (%v)", renewed, result.Status, result.ExpiresAt)
+	}
+}
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/keys"
+	"protonvpn-wg-confgen/pkg/timeutil"
+	"protonvpn-wg-confgen/pkg/validation"
+	"protonvpn-wg-confgen/pkg/wireguard"
+)
+
+// inspectOptions holds the flags of the inspect subcommand
+type inspectOptions struct {
+	Username    string
+	KeyProfile  string
+	KeyStoreDir string
+	JSON        bool
+}
+
+func (o *inspectOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Username, "username", "", "Only consider stored keys of this user")
+	fs.StringVar(&o.KeyProfile, "key-profile", "", "Only consider this key store profile")
+	fs.StringVar(&o.KeyStoreDir, "key-store-dir", "", "Directory for persisted device keys (default: ~/.protonvpn-keys)")
+	fs.BoolVar(&o.JSON, "json", false, "Print machine-readable JSON")
+}
+
+// Key check results
+const (
+	keyCheckMatch    = "match"
+	keyCheckMismatch = "mismatch"
+	keyCheckUnknown  = "unknown"
+)
+
+// inspectView is the JSON and text representation of an inspected configuration
+type inspectView struct {
+	File        string                  `json:"file"`
+	Config      *wireguard.ParsedConfig `json:"config"`
+	KeyCheck    string                  `json:"key_check"`
+	KeyProfile  string                  `json:"key_profile,omitempty"`
+	Username    string                  `json:"username,omitempty"`
+	Certificate *certificateView        `json:"certificate,omitempty"`
+	Notes       []string                `json:"notes,omitempty"`
+}
+
+// certificateView describes the stored certificate of a device
+type certificateView struct {
+	DeviceName            string    `json:"device_name"`
+	SerialNumber          string    `json:"serial_number"`
+	Fingerprint           string    `json:"fingerprint"`
+	RegisteredFingerprint string    `json:"registered_fingerprint,omitempty"`
+	Subject               string    `json:"subject,omitempty"`
+	NotBefore             time.Time `json:"not_before,omitempty"`
+	RefreshAt             time.Time `json:"refresh_at"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	Features              []string  `json:"features"`
+	MatchesKey            bool      `json:"matches_key"`
+}
+
+// RunInspect implements the inspect subcommand
+func RunInspect(args []string) error {
+	var opts inspectOptions
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: inspect [options] <config.conf>")
+	}
+
+	path := fs.Arg(0)
+	parsed, err := wireguard.ParseConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	view := inspectView{File: path, Config: parsed, KeyCheck: keyCheckUnknown}
+	store := keystore.NewStore(opts.KeyStoreDir, os.Getenv(constants.KeyPassphraseEnv))
+	entry, keyPair, err := findStoredKey(store, &opts, parsed.PrivateKey, &view)
+	if err != nil {
+		view.Notes = append(view.Notes, err.Error())
+	}
+	if entry != nil {
+		view.Username = entry.Username
+		view.KeyProfile = entry.Profile
+		view.Certificate = newCertificateView(&entry.Certificate, keyPair, &view)
+	}
+
+	if opts.JSON {
+		if err := printJSON(view); err != nil {
+			return err
+		}
+	} else {
+		printInspect(&view)
+	}
+
+	if view.KeyCheck == keyCheckMismatch {
+		return fmt.Errorf("the PrivateKey in %s does not correspond to the stored key for profile %s", path, view.KeyProfile)
+	}
+	return nil
+}
+
+// findStoredKey looks for the stored key the configuration was generated with, among the
+// entries selected by -username and -key-profile. A mismatch is only reported when
+// both flags select a single entry; otherwise the key check stays unknown.
+func findStoredKey(store *keystore.Store, opts *inspectOptions, privateKey string, view *inspectView) (*keystore.Entry, *keys.KeyPair, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	username := validation.CleanUsername(opts.Username)
+	var candidates []keystore.Entry
+	for _, entry := range entries {
+		if (username == "" || entry.Username == username) && (opts.KeyProfile == "" || entry.Profile == opts.KeyProfile) {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	var loadErr error
+	for _, candidate := range candidates {
+		entry, keyPair, err := store.Load(candidate.Username, candidate.Profile)
+		if err != nil {
+			if loadErr == nil {
+				loadErr = fmt.Errorf("could not load stored key for profile %s: %w", candidate.Profile, err)
+			}
+			continue
+		}
+		if keyPair.ToX25519Base64() == privateKey {
+			view.KeyCheck = keyCheckMatch
+			return entry, keyPair, nil
+		}
+		if username != "" && opts.KeyProfile != "" {
+			view.KeyCheck = keyCheckMismatch
+			return entry, keyPair, nil
+		}
+	}
+
+	if loadErr != nil {
+		return nil, nil, loadErr
+	}
+	return nil, nil, fmt.Errorf("no stored key matches the PrivateKey in this config")
+}
+
+// newCertificateView decodes the stored certificate and checks it against the stored key
+func newCertificateView(info *keystore.CertificateInfo, keyPair *keys.KeyPair, view *inspectView) *certificateView {
+	cert := &certificateView{
+		DeviceName:            info.DeviceName,
+		SerialNumber:          info.SerialNumber,
+		Fingerprint:           keyPair.Fingerprint(),
+		RegisteredFingerprint: info.Fingerprint,
+		RefreshAt:             info.RefreshAt(),
+		ExpiresAt:             info.ExpiresAt(),
+		Features:              api.GetCertificateFeatureNames(info.Features),
+	}
+	if cert.Features == nil {
+		cert.Features = []string{}
+	}
+
+	if info.Certificate == "" {
+		view.Notes = append(view.Notes, "no certificate PEM stored; regenerate the config to record it")
+		return cert
+	}
+
+	x509Cert, err := parseCertificatePEM(info.Certificate)
+	if err != nil {
+		view.Notes = append(view.Notes, err.Error())
+		return cert
+	}
+
+	cert.Subject = x509Cert.Subject.String()
+	cert.NotBefore = x509Cert.NotBefore
+	cert.ExpiresAt = x509Cert.NotAfter
+	if publicKey, ok := x509Cert.PublicKey.(ed25519.PublicKey); ok {
+		cert.Fingerprint = keys.PublicKeyFingerprint(publicKey)
+		cert.MatchesKey = publicKey.Equal(keyPair.PublicKey())
+	}
+	if !cert.MatchesKey {
+		view.Notes = append(view.Notes, "the stored certificate was not issued for the stored key")
+	}
+
+	return cert
+}
+
+// parseCertificatePEM decodes a PEM encoded X.509 certificate
+func parseCertificatePEM(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("stored certificate is not a PEM certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// printInspect prints an inspected configuration as text
+func printInspect(view *inspectView) {
+	cfg := view.Config
+
+	fmt.Printf("Config:      %s\n", view.File)
+	if !cfg.Generated.IsZero() {
+		fmt.Printf("Generated:   %s\n", cfg.Generated.Format(time.RFC3339))
+	}
+	if cfg.Device != "" {
+		fmt.Printf("Device:      %s\n", cfg.Device)
+	}
+
+	fmt.Printf("\nServer:      %s (%s, %s, %s tier)\n", cfg.ServerName, cfg.Country, cfg.City, cfg.Tier)
+	if cfg.SecureCore != "" {
+		fmt.Printf("Secure Core: %s\n", cfg.SecureCore)
+	}
+	if len(cfg.ServerFeatures) > 0 {
+		fmt.Printf("Features:    %s\n", strings.Join(cfg.ServerFeatures, ", "))
+	}
+	fmt.Printf("Physical:    %s\n", cfg.PhysicalServerID)
+	fmt.Printf("Endpoint:    %s\n", cfg.Endpoint)
+	fmt.Printf("Public key:  %s\n", cfg.PublicKey)
+
+	fmt.Printf("\nKey check:   %s", view.KeyCheck)
+	if view.KeyProfile != "" {
+		fmt.Printf(" (profile %s, user %s)", view.KeyProfile, view.Username)
+	}
+	fmt.Println()
+
+	if cert := view.Certificate; cert != nil {
+		fmt.Printf("\nCertificate: %s (serial %s)\n", cert.DeviceName, cert.SerialNumber)
+		if cert.Subject != "" {
+			fmt.Printf("Subject:     %s\n", cert.Subject)
+		}
+		fmt.Printf("Fingerprint: %s", cert.Fingerprint)
+		if cert.RegisteredFingerprint != "" && cert.RegisteredFingerprint == cert.Fingerprint {
+			fmt.Print(" (matches ClientKeyFingerprint)")
+		}
+		fmt.Println()
+		if cert.RegisteredFingerprint != "" && cert.RegisteredFingerprint != cert.Fingerprint {
+			fmt.Printf("Registered:  %s\n", cert.RegisteredFingerprint)
+		}
+		fmt.Printf("Refresh:     %s\n", cert.RefreshAt.Format(time.RFC3339))
+		fmt.Printf("Expires:     %s (%s)\n", cert.ExpiresAt.Format(time.RFC3339), describeExpiry(cert.ExpiresAt, time.Now()))
+		if len(cert.Features) > 0 {
+			fmt.Printf("Features:    %s\n", strings.Join(cert.Features, ", "))
+		} else if len(cfg.CertificateFeatures) > 0 {
+			fmt.Printf("Features:    %s (from config header)\n", strings.Join(cfg.CertificateFeatures, ", "))
+		}
+	} else if len(cfg.CertificateFeatures) > 0 {
+		fmt.Printf("Cert features: %s (from config header)\n", strings.Join(cfg.CertificateFeatures, ", "))
+	}
+
+	for _, note := range view.Notes {
+		fmt.Printf("Note: %s\n", note)
+	}
+}
+
+// describeExpiry returns "in 3 days" or "expired 2 hours ago"
+func describeExpiry(expiresAt, now time.Time) string {
+	if remaining := expiresAt.Sub(now); remaining > 0 {
+		return "in " + timeutil.HumanizeDuration(remaining)
+	}
+	return "expired " + timeutil.HumanizeDuration(now.Sub(expiresAt)) + " ago"
+}
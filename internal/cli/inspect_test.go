@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"protonvpn-wg-confgen/internal/keystore"
+	"protonvpn-wg-confgen/pkg/keys"
+	"protonvpn-wg-confgen/pkg/wireguard"
+)
+
+func TestFindStoredKey(t *testing.T) {
+	store := keystore.NewStore(t.TempDir(), "")
+
+	keyPair, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := store.Save(&keystore.Entry{Profile: "router", Username: "alice"}, keyPair); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	other, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       inspectOptions
+		privateKey string
+		want       string
+	}{
+		{"match", inspectOptions{}, keyPair.ToX25519Base64(), keyCheckMatch},
+		{"no match", inspectOptions{}, other.ToX25519Base64(), keyCheckUnknown},
+		{"explicit mismatch", inspectOptions{Username: "alice", KeyProfile: "router"}, other.ToX25519Base64(), keyCheckMismatch},
+		{"other user", inspectOptions{Username: "bob"}, keyPair.ToX25519Base64(), keyCheckUnknown},
+	}
+
+	for _, tt := range tests {
+		view := inspectView{KeyCheck: keyCheckUnknown}
+		_, _, _ = findStoredKey(store, &tt.opts, tt.privateKey, &view)
+		if view.KeyCheck != tt.want {
+			t.Errorf("%s: expected key check %s, got %s", tt.name, tt.want, view.KeyCheck)
+		}
+	}
+}
+
+func TestCertificateViewMatchesKey(t *testing.T) {
+	keyPair, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "router"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, keyPair.PublicKey(), keyPair.PrivateKey())
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	info := &keystore.CertificateInfo{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Fingerprint: keyPair.Fingerprint(),
+	}
+
+	var view inspectView
+	cert := newCertificateView(info, keyPair, &view)
+	if !cert.MatchesKey || cert.Fingerprint != keyPair.Fingerprint() || len(view.Notes) != 0 {
+		t.Errorf("Expected certificate to match the key, got %+v (notes: %v)", cert, view.Notes)
+	}
+
+	other, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if cert := newCertificateView(info, other, &view); cert.MatchesKey {
+		t.Error("Expected certificate not to match another key")
+	}
+}
+
+func TestInspectJSONOmitsPrivateKey(t *testing.T) {
+	parsed, err := wireguard.ParseConfig([]byte("[Interface]\nPrivateKey = secretKey=\nAddress = 10.2.0.2/32\n\n[Peer]\nPublicKey = serverKey=\n"))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	content, err := json.Marshal(inspectView{File: "wg0.conf", Config: parsed, KeyCheck: keyCheckUnknown})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(content), "secretKey=") || strings.Contains(string(content), "PrivateKey") {
+		t.Errorf("Expected the private key to be left out, got %s", content)
+	}
+	if !strings.Contains(string(content), `"public_key":"serverKey="`) || !strings.Contains(string(content), `"address":"10.2.0.2/32"`) {
+		t.Errorf("Expected snake_case config fields, got %s", content)
+	}
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/pkg/natpmp"
+)
+
+// portForwardOptions holds the flags of the portforward subcommand
+type portForwardOptions struct {
+	Gateway      string
+	Protocol     string
+	InternalPort int
+	Lifetime     time.Duration
+	Interval     time.Duration
+	PortFile     string
+	Hook         string
+	Once         bool
+}
+
+func (o *portForwardOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Gateway, "gateway", constants.PortForwardGateway, "NAT-PMP gateway address (host or host:port)")
+	fs.StringVar(&o.Protocol, "protocol", "both", "Protocols to map: udp, tcp or both")
+	fs.IntVar(&o.InternalPort, "internal-port", 0, "Internal port to map (0 = any, as used by ProtonVPN)")
+	fs.DurationVar(&o.Lifetime, "lifetime", constants.PortForwardLifetime*time.Second, "Requested mapping lifetime")
+	fs.DurationVar(&o.Interval, "interval", constants.PortForwardInterval*time.Second, "Renewal interval (must be shorter than -lifetime)")
+	fs.StringVar(&o.PortFile, "port-file", "", "Write the forwarded port to this file")
+	fs.StringVar(&o.Hook, "hook", "", "Shell command to run when the forwarded port changes ($PROTONVPN_PORT, $PROTONVPN_PREVIOUS_PORT)")
+	fs.BoolVar(&o.Once, "once", false, "Request the mapping once and exit instead of renewing it")
+}
+
+// protocols returns the mapping protocols selected with -protocol
+func (o *portForwardOptions) protocols() ([]natpmp.Protocol, error) {
+	switch o.Protocol {
+	case "udp":
+		return []natpmp.Protocol{natpmp.UDP}, nil
+	case "tcp":
+		return []natpmp.Protocol{natpmp.TCP}, nil
+	case "both":
+		return []natpmp.Protocol{natpmp.UDP, natpmp.TCP}, nil
+	default:
+		return nil, fmt.Errorf("invalid -protocol value: %s (expected udp, tcp or both)", o.Protocol)
+	}
+}
+
+// RunPortForward implements the portforward subcommand.
+// It must run while the tunnel is up; a certificate with port forwarding is required.
+func RunPortForward(args []string) error {
+	var opts portForwardOptions
+	fs := flag.NewFlagSet("portforward", flag.ContinueOnError)
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	protocols, err := opts.protocols()
+	if err != nil {
+		return err
+	}
+	if opts.InternalPort < 0 || opts.InternalPort > 65535 {
+		return fmt.Errorf("invalid -internal-port: %d", opts.InternalPort)
+	}
+	if !opts.Once && opts.Interval >= opts.Lifetime {
+		return fmt.Errorf("-interval (%s) must be shorter than -lifetime (%s)", opts.Interval, opts.Lifetime)
+	}
+
+	client := natpmp.NewClient(opts.Gateway)
+	if address, err := client.ExternalAddress(); err != nil {
+		fmt.Printf("Warning: Failed to get the external address: %v\n", err)
+	} else {
+		fmt.Printf("External address: %s\n", address)
+	}
+
+	forwarder := &portForwarder{
+		mapper:    client,
+		opts:      opts,
+		protocols: protocols,
+	}
+
+	if err := forwarder.renew(); err != nil {
+		return err
+	}
+	if opts.Once {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	timer := time.NewTimer(forwarder.renewDelay())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopping port forwarding")
+			return nil
+		case <-timer.C:
+			if err := forwarder.renew(); err != nil {
+				fmt.Printf("Warning: Failed to renew port mapping: %v\n", err)
+			}
+			timer.Reset(forwarder.renewDelay())
+		}
+	}
+}
+
+// portMapper requests port mappings from a NAT-PMP gateway
+type portMapper interface {
+	AddMapping(protocol natpmp.Protocol, internalPort, externalPort uint16, lifetime time.Duration) (*natpmp.Mapping, error)
+}
+
+// portForwarder keeps the port mappings alive and reports port changes
+type portForwarder struct {
+	mapper    portMapper
+	opts      portForwardOptions
+	protocols []natpmp.Protocol
+	port      uint16
+	lifetime  time.Duration // Shortest lifetime granted by the last renewal
+}
+
+// renewDelay returns the time until the next renewal: -interval, or half the granted
+// lifetime when the gateway granted less than requested
+func (f *portForwarder) renewDelay() time.Duration {
+	if f.lifetime > 0 && f.lifetime/2 < f.opts.Interval {
+		return f.lifetime / 2
+	}
+	return f.opts.Interval
+}
+
+// renew requests the mappings for all protocols and handles a change of the forwarded port
+func (f *portForwarder) renew() error {
+	var port uint16
+	var lifetime time.Duration
+	for _, protocol := range f.protocols {
+		mapping, err := f.mapper.AddMapping(protocol, uint16(f.opts.InternalPort),
+			constants.PortForwardExternalPort, f.opts.Lifetime)
+		if err != nil {
+			return fmt.Errorf("%s mapping failed: %w", protocol, err)
+		}
+		if port != 0 && mapping.ExternalPort != port {
+			fmt.Printf("Warning: %s mapping got port %d, but the previous protocol got %d\n",
+				protocol, mapping.ExternalPort, port)
+		}
+		port = mapping.ExternalPort
+		if lifetime == 0 || mapping.Lifetime < lifetime {
+			lifetime = mapping.Lifetime
+		}
+	}
+	f.lifetime = lifetime
+
+	if port == f.port {
+		return nil
+	}
+
+	fmt.Printf("Forwarded port: %d\n", port)
+
+	// Only remember the port once it is recorded, so a failed write is retried on the next renewal
+	if f.opts.PortFile != "" {
+		if err := writePortFile(f.opts.PortFile, port); err != nil {
+			return err
+		}
+	}
+	previous := f.port
+	f.port = port
+
+	if f.opts.Hook != "" {
+		if err := runPortHook(f.opts.Hook, port, previous); err != nil {
+			fmt.Printf("Warning: Port change hook failed: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// writePortFile atomically replaces the port file with the forwarded port
+func writePortFile(path string, port uint16) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".port-*")
+	if err != nil {
+		return fmt.Errorf("failed to write port file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := fmt.Fprintf(tmp, "%d\n", port); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write port file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write port file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write port file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write port file: %w", err)
+	}
+	return nil
+}
+
+// runPortHook runs the hook through the shell with the new and previous port in the environment
+func runPortHook(hook string, port, previous uint16) error {
+	cmd := exec.Command("/bin/sh", "-c", hook)
+	cmd.Env = append(os.Environ(),
+		"PROTONVPN_PORT="+strconv.Itoa(int(port)),
+		"PROTONVPN_PREVIOUS_PORT="+strconv.Itoa(int(previous)),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
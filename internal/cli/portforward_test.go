@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonvpn-wg-confgen/pkg/natpmp"
+)
+
+// fakeMapper grants the next port of ports to every mapping request
+type fakeMapper struct {
+	ports    []uint16
+	lifetime time.Duration
+	requests int
+}
+
+func (m *fakeMapper) AddMapping(protocol natpmp.Protocol, internalPort, _ uint16, _ time.Duration) (*natpmp.Mapping, error) {
+	port := m.ports[m.requests/2]
+	m.requests++
+	return &natpmp.Mapping{Protocol: protocol, InternalPort: internalPort, ExternalPort: port, Lifetime: m.lifetime}, nil
+}
+
+func TestWritePortFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "port")
+	for _, port := range []uint16{51820, 40000} {
+		if err := writePortFile(path, port); err != nil {
+			t.Fatalf("writePortFile failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != "40000\n" {
+		t.Errorf("Expected the last port, got %q", data)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected no temporary files to remain, got %v, %v", entries, err)
+	}
+}
+
+func TestPortForwarderRenew(t *testing.T) {
+	dir := t.TempDir()
+	hookLog := filepath.Join(dir, "hook.log")
+	mapper := &fakeMapper{ports: []uint16{40000, 40000, 41000}, lifetime: 60 * time.Second}
+	forwarder := &portForwarder{
+		mapper: mapper,
+		opts: portForwardOptions{
+			Interval: 45 * time.Second,
+			PortFile: filepath.Join(dir, "port"),
+			Hook:     `echo "$PROTONVPN_PORT $PROTONVPN_PREVIOUS_PORT" >> ` + hookLog,
+		},
+		protocols: []natpmp.Protocol{natpmp.UDP, natpmp.TCP},
+	}
+
+	for range mapper.ports {
+		if err := forwarder.renew(); err != nil {
+			t.Fatalf("renew failed: %v", err)
+		}
+	}
+
+	// The hook only runs when the port changes, with the previous port (0 at first)
+	data, err := os.ReadFile(hookLog)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || lines[0] != "40000 0" || lines[1] != "41000 40000" {
+		t.Errorf("Unexpected hook invocations: %q", lines)
+	}
+	if data, err := os.ReadFile(forwarder.opts.PortFile); err != nil || string(data) != "41000\n" {
+		t.Errorf("Expected port file with 41000, got %q, %v", data, err)
+	}
+}
+
+func TestPortForwarderRenewDelay(t *testing.T) {
+	tests := []struct {
+		lifetime time.Duration
+		want     time.Duration
+	}{
+		{0, 45 * time.Second},                 // Nothing granted yet
+		{120 * time.Second, 45 * time.Second}, // Half the lifetime is longer than -interval
+		{60 * time.Second, 30 * time.Second},  // The gateway granted less than requested
+	}
+
+	for _, tt := range tests {
+		forwarder := &portForwarder{opts: portForwardOptions{Interval: 45 * time.Second}, lifetime: tt.lifetime}
+		if got := forwarder.renewDelay(); got != tt.want {
+			t.Errorf("Lifetime %s: expected delay %s, got %s", tt.lifetime, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/pkg/timeutil"
+)
+
+// CertPolicy sets the certificate mode and duration for a kind of device
+type CertPolicy struct {
+	Name        string `json:"name"`
+	Mode        string `json:"mode"`                   // persistent or session
+	Duration    string `json:"duration"`               // Used unless -duration is given
+	MaxDuration string `json:"max_duration,omitempty"` // Upper bound for -duration
+}
+
+// builtinCertPolicies are available without a profiles file
+var builtinCertPolicies = []CertPolicy{
+	{Name: constants.CertPolicyLaptop, Mode: constants.CertModeSession, Duration: "24h", MaxDuration: "7d"},
+	{Name: constants.CertPolicyRouter, Mode: constants.CertModePersistent, Duration: "365d"},
+}
+
+// findCertPolicy returns the named policy, preferring policies from the profiles file over built-in ones
+func findCertPolicy(name string, profiles *ProfileSet) (*CertPolicy, error) {
+	if profiles != nil {
+		for i := range profiles.CertPolicies {
+			if profiles.CertPolicies[i].Name == name {
+				return &profiles.CertPolicies[i], nil
+			}
+		}
+	}
+	for i := range builtinCertPolicies {
+		if builtinCertPolicies[i].Name == name {
+			policy := builtinCertPolicies[i]
+			return &policy, nil
+		}
+	}
+	return nil, fmt.Errorf("certificate policy not found: %s", name)
+}
+
+// validate checks the policy's mode and durations against the API limits
+func (p *CertPolicy) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("certificate policy has no name")
+	}
+	if err := validateCertMode(p.Mode); err != nil {
+		return fmt.Errorf("certificate policy %s: %w", p.Name, err)
+	}
+	if _, err := timeutil.ParseToMinutes(p.Duration); err != nil {
+		return fmt.Errorf("certificate policy %s: invalid duration: %w", p.Name, err)
+	}
+	if p.MaxDuration != "" {
+		if _, err := timeutil.ParseToMinutes(p.MaxDuration); err != nil {
+			return fmt.Errorf("certificate policy %s: invalid max_duration: %w", p.Name, err)
+		}
+		return p.checkDuration(p.Duration)
+	}
+	return nil
+}
+
+// checkDuration checks a duration against the policy's maximum
+func (p *CertPolicy) checkDuration(duration string) error {
+	if p.MaxDuration == "" {
+		return nil
+	}
+
+	// Both values have been validated by ParseToMinutes
+	requested, _ := timeutil.ParseDuration(duration)
+	limit, _ := timeutil.ParseDuration(p.MaxDuration)
+	if requested > limit {
+		return fmt.Errorf("certificate duration %s exceeds the %s policy maximum of %s", duration, p.Name, p.MaxDuration)
+	}
+	return nil
+}
+
+func validateCertMode(mode string) error {
+	switch mode {
+	case constants.CertModePersistent, constants.CertModeSession:
+		return nil
+	default:
+		return fmt.Errorf("invalid certificate mode: %s (expected persistent or session)", mode)
+	}
+}
+
+// applyCertPolicy resolves the certificate mode and duration. The policy comes from
+// -cert-policy or the active profile; -cert-mode and -duration override its values
+// when given explicitly. The resulting duration is validated with timeutil.ParseToMinutes.
+func applyCertPolicy(cfg *Config, profiles *ProfileSet, explicit map[string]bool) error {
+	if cfg.CertPolicy != "" {
+		policy, err := findCertPolicy(cfg.CertPolicy, profiles)
+		if err != nil {
+			return err
+		}
+		if !explicit["cert-mode"] {
+			cfg.CertMode = policy.Mode
+		}
+		if !explicit["duration"] {
+			cfg.Duration = policy.Duration
+		}
+		if err := policy.checkDuration(cfg.Duration); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CertMode == "" {
+		cfg.CertMode = constants.CertMode
+	}
+	if err := validateCertMode(cfg.CertMode); err != nil {
+		return err
+	}
+
+	if _, err := timeutil.ParseToMinutes(cfg.Duration); err != nil {
+		return fmt.Errorf("invalid -duration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+func TestApplyCertPolicy(t *testing.T) {
+	profiles := &ProfileSet{CertPolicies: []CertPolicy{
+		{Name: "kiosk", Mode: constants.CertModeSession, Duration: "8h", MaxDuration: "12h"},
+	}}
+
+	tests := []struct {
+		name         string
+		cfg          Config
+		explicit     map[string]bool
+		wantMode     string
+		wantDuration string
+		wantErr      bool
+	}{
+		{"default", Config{Duration: "365d"}, nil, constants.CertModePersistent, "365d", false},
+		{"laptop", Config{Duration: "365d", CertPolicy: "laptop"}, nil, constants.CertModeSession, "24h", false},
+		{"laptop with duration", Config{Duration: "3d", CertPolicy: "laptop"}, map[string]bool{"duration": true}, constants.CertModeSession, "3d", false},
+		{"laptop over max", Config{Duration: "30d", CertPolicy: "laptop"}, map[string]bool{"duration": true}, "", "", true},
+		{"router", Config{Duration: "30d", CertPolicy: "router"}, nil, constants.CertModePersistent, "365d", false},
+		{"mode override", Config{Duration: "365d", CertPolicy: "router", CertMode: "session"}, map[string]bool{"cert-mode": true}, constants.CertModeSession, "365d", false},
+		{"custom", Config{Duration: "365d", CertPolicy: "kiosk"}, nil, constants.CertModeSession, "8h", false},
+		{"unknown policy", Config{Duration: "365d", CertPolicy: "phone"}, nil, "", "", true},
+		{"invalid mode", Config{Duration: "365d", CertMode: "temporary"}, nil, "", "", true},
+		{"duration too long", Config{Duration: "400d"}, nil, "", "", true},
+	}
+
+	for _, tt := range tests {
+		err := applyCertPolicy(&tt.cfg, profiles, tt.explicit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if err == nil && (tt.cfg.CertMode != tt.wantMode || tt.cfg.Duration != tt.wantDuration) {
+			t.Errorf("%s: expected %s/%s, got %s/%s", tt.name, tt.wantMode, tt.wantDuration, tt.cfg.CertMode, tt.cfg.Duration)
+		}
+	}
+}
+
+func TestCertPolicyValidate(t *testing.T) {
+	invalid := []CertPolicy{
+		{Name: "a", Mode: "forever", Duration: "1d"},
+		{Name: "b", Mode: constants.CertModeSession, Duration: "0m"},
+		{Name: "c", Mode: constants.CertModeSession, Duration: "2d", MaxDuration: "1d"},
+		{Mode: constants.CertModeSession, Duration: "1d"},
+	}
+	for _, policy := range invalid {
+		if err := policy.validate(); err == nil {
+			t.Errorf("Expected policy %+v to be invalid", policy)
+		}
+	}
+
+	for _, policy := range builtinCertPolicies {
+		if err := policy.validate(); err != nil {
+			t.Errorf("Built-in policy %s is invalid: %v", policy.Name, err)
+		}
+	}
+}
@@ -2,14 +2,13 @@
 package config
 
 import (
-	"flag" 
-	// existing flags...
-    flag.BoolVar(&cfg.FreeOnly, "free-only", false, "Use only Free tier servers (tier 0)")
-    // 新增：
-    flag.BoolVar(&cfg.ListAllServers, "list-all-servers", false, "List all servers from API (ignore -countries filter) and exit")
+	"flag"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"protonvpn-wg-confgen/internal/constants"
 	"protonvpn-wg-confgen/pkg/validation"
@@ -22,24 +21,47 @@ func Parse() (*Config, error) {
 	var countriesFlag string
 	var dnsServersFlag string
 	var allowedIPsFlag string
+	var devicesFlag string
+	var formatFlag string
+	var listenersFlag string
 
 	// Set default DNS and allowed IPs based on IPv6 support
 	defaultDNS := constants.DefaultDNSIPv4
 	defaultAllowedIPs := constants.DefaultAllowedIPsIPv4
 
-	// Authentication flags
-	flag.StringVar(&cfg.Username, "username", "", "ProtonVPN username")
-	flag.StringVar(&cfg.Password, "password", "", "ProtonVPN password (will prompt if not provided)")
+	// Authentication, session and API flags shared with subcommands
+	registerCommonFlags(flag.CommandLine, cfg)
 
 	// Server selection flags
 	flag.StringVar(&countriesFlag, "countries", "", "Comma-separated list of country codes (e.g., US,NL,CH)")
 	flag.BoolVar(&cfg.P2PServersOnly, "p2p-only", constants.DefaultP2POnly, "Use only P2P-enabled servers")
 	flag.BoolVar(&cfg.SecureCoreOnly, "secure-core", false, "Use only Secure Core servers (multi-hop through privacy-friendly countries)")
 	flag.BoolVar(&cfg.FreeOnly, "free-only", false, "Use only Free tier servers (tier 0)")
+	flag.BoolVar(&cfg.StreamingOnly, "streaming-only", false, "Use only Streaming-optimized servers")
+	flag.BoolVar(&cfg.ListAllServers, "list-all-servers", false, "List all servers from API (ignore -countries filter) and exit")
+	flag.StringVar(&cfg.ServerName, "server", "", "Pin a specific logical server by name (e.g., US-CA#42), bypassing ranking")
+	flag.StringVar(&cfg.PhysicalServerID, "physical-id", "", "Pin a specific physical server by ID, bypassing ranking")
+
+	// Selection profiles
+	flag.StringVar(&cfg.ProfilesFile, "profiles", "", "JSON file with schedule-based selection profiles (active profile is picked from the local clock)")
+	flag.StringVar(&cfg.ProfileName, "profile", "", "Use the named profile from -profiles regardless of the schedule")
 
 	// Output configuration
 	flag.StringVar(&cfg.OutputFile, "output", "protonvpn.conf", "Output WireGuard configuration file")
+	flag.StringVar(&formatFlag, "format", constants.DefaultOutputFormat, "Comma-separated output formats, each optionally with its own file (e.g., wg-quick,json=proton.json). Formats: wg-quick, wg, json, networkd, networkmanager, openwrt, routeros, opnsense, pfsense, gluetun, compose, kubernetes, sing-box, xray, mihomo, wireproxy")
+	flag.StringVar(&cfg.InterfaceName, "interface", constants.DefaultInterfaceName, "Interface name for output formats that create the interface (e.g., networkd)")
+	flag.StringVar(&cfg.NMConnectionDir, "nm-dir", "", "Write the networkmanager output into this directory (e.g., /etc/NetworkManager/system-connections)")
+	flag.BoolVar(&cfg.NMAutoconnect, "nm-autoconnect", true, "Let NetworkManager activate the networkmanager connection automatically")
+	flag.BoolVar(&cfg.FirewallZone, "firewall-zone", false, "Add a firewall zone (masquerading, forwarding from lan) to the openwrt output")
+	flag.StringVar(&cfg.SecretKey, "secret-key", constants.DefaultSecretKey, "Key of the wg-quick configuration in the kubernetes output")
+	flag.StringVar(&listenersFlag, "proxy-listeners", constants.DefaultProxyListeners, "Comma-separated local proxies of the wireproxy output, each optionally with its bind address (e.g., socks5,http=127.0.0.1:3128)")
+	flag.StringVar(&cfg.QR, "qr", "", "Show the wg-quick configuration as a QR code: terminal, or a .png or .svg file")
+	flag.BoolVar(&cfg.QRStrip, "qr-strip", false, "Leave the metadata comments out of the QR code")
+	flag.IntVar(&cfg.GroupSize, "group", 1, "Number of servers in the sing-box, xray and mihomo outputs (selectable in sing-box and mihomo)")
 	flag.StringVar(&cfg.DeviceName, "device-name", "", "Device name for WireGuard config (auto-generated if empty)")
+	flag.StringVar(&devicesFlag, "devices", "", "Comma-separated list of device names to generate configs for in one run")
+	flag.StringVar(&cfg.AntiAffinity, "distinct", constants.AntiAffinityNone, "Require distinct servers across -devices: none, server or city")
+	flag.StringVar(&cfg.ConnectionLimit, "connection-limit", constants.ConnectionLimitWarn, "What to do when new devices would exceed the plan's connection limit: warn, refuse or ignore")
 
 	// Network configuration
 	flag.BoolVar(&cfg.EnableIPv6, "ipv6", false, "Enable IPv6 support")
@@ -47,34 +69,93 @@ func Parse() (*Config, error) {
 	flag.StringVar(&allowedIPsFlag, "allowed-ips", "", "Comma-separated list of allowed IPs (defaults based on IPv6 setting)")
 	flag.BoolVar(&cfg.EnableAccelerator, "accelerator", true, "Enable VPN accelerator")
 
+	// Certificate features
+	flag.IntVar(&cfg.NetShieldLevel, "netshield", constants.NetShieldOff, "NetShield level: 0 = off, 1 = block malware, 2 = block ads, trackers and malware")
+	flag.BoolVar(&cfg.ModerateNAT, "moderate-nat", false, "Enable Moderate NAT (for gaming and peer-to-peer applications)")
+	flag.BoolVar(&cfg.PortForwarding, "port-forwarding", false, "Enable port forwarding (requires a P2P server)")
+	flag.BoolVar(&cfg.Bouncing, "bouncing", false, "Request the bouncing feature in the certificate")
+
 	// Certificate configuration
 	flag.StringVar(&cfg.Duration, "duration", constants.DefaultCertDuration, "Certificate duration (e.g., 30m, 24h, 7d, 1h30m). Max: 365d")
+	flag.StringVar(&cfg.CertMode, "cert-mode", "", "Certificate mode: persistent (listed as a device) or session (short-lived). Default: persistent, or from -cert-policy")
+	flag.StringVar(&cfg.CertPolicy, "cert-policy", "", "Certificate policy setting mode and duration: laptop (session, 24h, max 7d), router (persistent, 365d) or one from -profiles")
 
-	// Session management
-	flag.BoolVar(&cfg.ClearSession, "clear-session", false, "Clear saved session and force re-authentication")
-	flag.BoolVar(&cfg.NoSession, "no-session", false, "Don't save or use session persistence")
-	flag.BoolVar(&cfg.ForceRefresh, "force-refresh", false, "Force session refresh even if not expired")
-	flag.StringVar(&cfg.SessionDuration, "session-duration", "0", "Session cache duration (e.g., 12h, 24h, 7d). 0 = no expiration")
-
-	// Advanced configuration
-	flag.StringVar(&cfg.APIURL, "api-url", constants.DefaultAPIURL, "ProtonVPN API URL")
-	flag.BoolVar(&cfg.Debug, "debug", false, "Enable debug output")
+	// Key persistence
+	flag.BoolVar(&cfg.NoKeyStore, "no-key-store", false, "Don't persist the device key (registers a new device on every run)")
+	flag.BoolVar(&cfg.RotateKey, "rotate-key", false, "Generate and register a new key even if a stored key is still valid")
+	flag.BoolVar(&cfg.Renew, "renew", false, "Renewal mode: re-register only when the certificate needs refreshing and rewrite the config only if the server or key changed (exit code 3 when unchanged)")
+	flag.StringVar(&cfg.KeyStoreDir, "key-store-dir", "", "Directory for persisted device keys (default: ~/.protonvpn-keys)")
+	flag.StringVar(&cfg.PrivateKeyFile, "private-key-file", "", "Import an Ed25519 private key (PKCS #8 PEM or base64) from this file, or - for stdin")
+	flag.StringVar(&cfg.PrivateKeyEnv, "private-key-env", "", "Import an Ed25519 private key from this environment variable")
+	flag.StringVar(&cfg.KeyProfile, "key-profile", "", "Key store profile name (defaults to the device name, or \"default\")")
 
 	flag.Parse()
 
-	// Validate required flags
-	if countriesFlag == "" {
-		return nil, fmt.Errorf("countries flag is required")
+	cfg.Countries = parseCountries(countriesFlag)
+
+	// Apply the active selection profile on top of the flags
+	profiles, err := applyProfile(cfg, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse and validate country codes
-	cfg.Countries = parseCountries(countriesFlag)
+	// Resolve certificate mode and duration; explicitly given flags override the policy
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if err := applyCertPolicy(cfg, profiles, explicit); err != nil {
+		return nil, err
+	}
+
+	// Validate required flags (a pinned server or -list-all-servers makes the country filter unnecessary)
+	if len(cfg.Countries) == 0 && !cfg.HasPinnedServer() && !cfg.ListAllServers {
+		return nil, fmt.Errorf("countries flag is required (unless -server, -physical-id or -list-all-servers is set)")
+	}
+
+	// Validate country codes
 	for _, country := range cfg.Countries {
 		if !validation.IsValidCountryCode(country) {
 			return nil, fmt.Errorf("invalid country code: %s", country)
 		}
 	}
 
+	if err := validateFeatures(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Outputs, err = parseOutputs(formatFlag); err != nil {
+		return nil, err
+	}
+	if !validation.IsValidInterfaceName(cfg.InterfaceName) {
+		return nil, fmt.Errorf("invalid -interface name: %s (at most 15 letters, digits, '-', '_' or '.')", cfg.InterfaceName)
+	}
+	if cfg.ProxyListeners, err = parseProxyListeners(listenersFlag); err != nil {
+		return nil, err
+	}
+	if err := validateQR(cfg.QR); err != nil {
+		return nil, err
+	}
+	if cfg.GroupSize < 1 {
+		return nil, fmt.Errorf("invalid -group size: %d (must be at least 1)", cfg.GroupSize)
+	}
+
+	// Parse multi-device settings
+	cfg.Devices = parseCommaSeparatedList(devicesFlag)
+	if err := validateAntiAffinity(cfg); err != nil {
+		return nil, err
+	}
+	switch cfg.ConnectionLimit {
+	case constants.ConnectionLimitWarn, constants.ConnectionLimitRefuse, constants.ConnectionLimitIgnore:
+	default:
+		return nil, fmt.Errorf("invalid -connection-limit value: %s (expected warn, refuse or ignore)", cfg.ConnectionLimit)
+	}
+
+	if cfg.Renew && cfg.NoKeyStore {
+		return nil, fmt.Errorf("-renew requires the key store and cannot be combined with -no-key-store")
+	}
+	if err := validateKeyImport(cfg); err != nil {
+		return nil, err
+	}
+
 	// Set defaults based on IPv6 setting
 	if cfg.EnableIPv6 {
 		defaultDNS = fmt.Sprintf("%s,%s", constants.DefaultDNSIPv4, constants.DefaultDNSIPv6)
@@ -99,6 +180,127 @@ func Parse() (*Config, error) {
 	return cfg, nil
 }
 
+// validateFeatures checks the requested certificate features against the server selection flags
+func validateFeatures(cfg *Config) error {
+	if cfg.NetShieldLevel < constants.NetShieldOff || cfg.NetShieldLevel > constants.NetShieldAdsMalware {
+		return fmt.Errorf("invalid -netshield level: %d (expected 0, 1 or 2)", cfg.NetShieldLevel)
+	}
+
+	if !cfg.PortForwarding {
+		return nil
+	}
+	switch {
+	case cfg.SecureCoreOnly:
+		return fmt.Errorf("-port-forwarding is not available on Secure Core servers")
+	case cfg.FreeOnly:
+		return fmt.Errorf("-port-forwarding is not available on Free tier servers")
+	case !cfg.P2PServersOnly && !cfg.HasPinnedServer():
+		return fmt.Errorf("-port-forwarding requires P2P servers; remove -p2p-only=false")
+	}
+	return nil
+}
+
+// validateKeyImport checks that an imported key is used for a single device
+func validateKeyImport(cfg *Config) error {
+	if cfg.PrivateKeyFile == "" && cfg.PrivateKeyEnv == "" {
+		return nil
+	}
+
+	switch {
+	case cfg.PrivateKeyFile != "" && cfg.PrivateKeyEnv != "":
+		return fmt.Errorf("-private-key-file and -private-key-env are mutually exclusive")
+	case cfg.RotateKey:
+		return fmt.Errorf("-rotate-key cannot be combined with an imported private key")
+	case len(cfg.Devices) > 0:
+		return fmt.Errorf("an imported private key can only be used for one device, not with -devices")
+	}
+	return nil
+}
+
+// registerCommonFlags registers the authentication, session management and API flags
+func registerCommonFlags(fs *flag.FlagSet, cfg *Config) {
+	// Authentication flags
+	fs.StringVar(&cfg.Username, "username", "", "ProtonVPN username")
+	fs.StringVar(&cfg.Password, "password", "", "ProtonVPN password (will prompt if not provided)")
+
+	// Session management
+	fs.BoolVar(&cfg.ClearSession, "clear-session", false, "Clear saved session and force re-authentication")
+	fs.BoolVar(&cfg.NoSession, "no-session", false, "Don't save or use session persistence")
+	fs.BoolVar(&cfg.ForceRefresh, "force-refresh", false, "Force session refresh even if not expired")
+	fs.StringVar(&cfg.SessionDuration, "session-duration", "0", "Session cache duration (e.g., 12h, 24h, 7d). 0 = no expiration")
+
+	// Advanced configuration
+	fs.StringVar(&cfg.APIURL, "api-url", constants.DefaultAPIURL, "ProtonVPN API URL")
+	fs.BoolVar(&cfg.Debug, "debug", false, "Enable debug output")
+}
+
+// ParseCommand parses the arguments of a subcommand and returns the remaining positional arguments.
+// The authentication, session and API flags are always available; register adds the subcommand's own flags.
+func ParseCommand(name string, args []string, register func(fs *flag.FlagSet, cfg *Config)) (*Config, []string, error) {
+	cfg := &Config{}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	registerCommonFlags(fs, cfg)
+	if register != nil {
+		register(fs, cfg)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// Clean up username
+	cfg.Username = validation.CleanUsername(cfg.Username)
+
+	return cfg, fs.Args(), nil
+}
+
+// applyProfile applies the profile forced with -profile, or the one scheduled for now.
+// It returns the loaded profiles file, or nil if none was given.
+func applyProfile(cfg *Config, now time.Time) (*ProfileSet, error) {
+	if cfg.ProfilesFile == "" {
+		if cfg.ProfileName != "" {
+			return nil, fmt.Errorf("-profile requires -profiles")
+		}
+		return nil, nil
+	}
+
+	profiles, err := LoadProfiles(cfg.ProfilesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.ProfileName != "" {
+		profile, err := profiles.Find(cfg.ProfileName)
+		if err != nil {
+			return nil, err
+		}
+		profile.Apply(cfg)
+		return profiles, nil
+	}
+
+	if profile := profiles.Active(now); profile != nil {
+		profile.Apply(cfg)
+	}
+	return profiles, nil
+}
+
+// validateAntiAffinity checks the -distinct value and its compatibility with other flags
+func validateAntiAffinity(cfg *Config) error {
+	switch cfg.AntiAffinity {
+	case constants.AntiAffinityNone:
+		return nil
+	case constants.AntiAffinityServer, constants.AntiAffinityCity:
+	default:
+		return fmt.Errorf("invalid -distinct value: %s (expected none, server or city)", cfg.AntiAffinity)
+	}
+
+	if cfg.HasPinnedServer() && len(cfg.Devices) > 1 {
+		return fmt.Errorf("-distinct cannot be combined with -server or -physical-id for multiple devices")
+	}
+	return nil
+}
+
 // parseCommaSeparatedList parses a comma-separated string into a trimmed slice
 func parseCommaSeparatedList(input string) []string {
 	parts := strings.Split(input, ",")
@@ -112,6 +314,67 @@ func parseCommaSeparatedList(input string) []string {
 	return result
 }
 
+// parseOutputs parses the -format list of format[=path] entries.
+// Format names are validated by the wireguard package, which renders them.
+func parseOutputs(input string) ([]Output, error) {
+	var outputs []Output
+	for _, entry := range parseCommaSeparatedList(input) {
+		format, path, _ := strings.Cut(entry, "=")
+		output := Output{Format: strings.TrimSpace(format), Path: strings.TrimSpace(path)}
+		if output.Format == "" {
+			return nil, fmt.Errorf("invalid -format entry: %s", entry)
+		}
+		outputs = append(outputs, output)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("-format requires at least one output format")
+	}
+	return outputs, nil
+}
+
+// parseProxyListeners parses the -proxy-listeners list of type[=address] entries
+func parseProxyListeners(input string) ([]ProxyListener, error) {
+	var listeners []ProxyListener
+	seen := make(map[string]bool)
+	for _, entry := range parseCommaSeparatedList(input) {
+		listenerType, address, _ := strings.Cut(entry, "=")
+		listener := ProxyListener{Type: strings.ToLower(strings.TrimSpace(listenerType)), Address: strings.TrimSpace(address)}
+		switch listener.Type {
+		case constants.ProxyListenerSOCKS5:
+			if listener.Address == "" {
+				listener.Address = constants.DefaultSOCKS5Address
+			}
+		case constants.ProxyListenerHTTP:
+			if listener.Address == "" {
+				listener.Address = constants.DefaultHTTPAddress
+			}
+		default:
+			return nil, fmt.Errorf("invalid -proxy-listeners entry: %s (expected socks5 or http)", entry)
+		}
+		if _, _, err := net.SplitHostPort(listener.Address); err != nil {
+			return nil, fmt.Errorf("invalid -proxy-listeners address: %s (expected host:port)", listener.Address)
+		}
+		if seen[listener.Type] {
+			return nil, fmt.Errorf("-proxy-listeners lists %s more than once", listener.Type)
+		}
+		seen[listener.Type] = true
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// validateQR checks that -qr is "terminal" or an image file name
+func validateQR(qr string) error {
+	switch strings.ToLower(filepath.Ext(qr)) {
+	case ".png", ".svg":
+		return nil
+	}
+	if qr == "" || qr == constants.QRTerminal {
+		return nil
+	}
+	return fmt.Errorf("invalid -qr value: %s (expected terminal, or a .png or .svg file)", qr)
+}
+
 // parseCountries parses and normalizes country codes
 func parseCountries(countriesFlag string) []string {
 	return parseCommaSeparatedList(strings.ToUpper(countriesFlag))
@@ -119,6 +382,14 @@ func parseCountries(countriesFlag string) []string {
 
 // PrintUsage prints usage information
 func PrintUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s -username <username> -countries <country-codes> [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s -username <username> -countries <country-codes> [options]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s <command> [options] [arguments]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  agent         Show the connection status or change features of the running tunnel\n")
+	fmt.Fprintf(os.Stderr, "  check-expiry  Warn or fail when certificates of configs or stored keys expire soon\n")
+	fmt.Fprintf(os.Stderr, "  devices       List, show, revoke and prune persistent WireGuard devices\n")
+	fmt.Fprintf(os.Stderr, "  inspect       Show the server, certificate and key status of a generated config\n")
+	fmt.Fprintf(os.Stderr, "  portforward   Request and renew a forwarded port over NAT-PMP (run with the tunnel up)\n\n")
+	fmt.Fprintf(os.Stderr, "Options:\n")
 	flag.PrintDefaults()
 }
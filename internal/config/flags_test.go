@@ -0,0 +1,134 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/pkg/validation"
+)
+
+func TestValidateFeatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"defaults", Config{P2PServersOnly: true}, false},
+		{"netshield ads", Config{NetShieldLevel: 2}, false},
+		{"netshield invalid", Config{NetShieldLevel: 3}, true},
+		{"port forwarding on p2p", Config{PortForwarding: true, P2PServersOnly: true}, false},
+		{"port forwarding without p2p", Config{PortForwarding: true}, true},
+		{"port forwarding pinned", Config{PortForwarding: true, ServerName: "CH#1"}, false},
+		{"port forwarding secure core", Config{PortForwarding: true, P2PServersOnly: true, SecureCoreOnly: true}, true},
+		{"port forwarding free", Config{PortForwarding: true, P2PServersOnly: true, FreeOnly: true}, true},
+	}
+
+	for _, tt := range tests {
+		if err := validateFeatures(&tt.cfg); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateKeyImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"no import", Config{RotateKey: true, Devices: []string{"a", "b"}}, false},
+		{"file", Config{PrivateKeyFile: "key.pem"}, false},
+		{"file and env", Config{PrivateKeyFile: "key.pem", PrivateKeyEnv: "KEY"}, true},
+		{"rotate", Config{PrivateKeyEnv: "KEY", RotateKey: true}, true},
+		{"devices", Config{PrivateKeyFile: "-", Devices: []string{"a", "b"}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := validateKeyImport(&tt.cfg); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	outputs, err := parseOutputs("wg-quick, json=proton.json")
+	if err != nil {
+		t.Fatalf("parseOutputs failed: %v", err)
+	}
+	want := []Output{{Format: "wg-quick"}, {Format: "json", Path: "proton.json"}}
+	if len(outputs) != len(want) || outputs[0] != want[0] || outputs[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, outputs)
+	}
+
+	for _, input := range []string{"", "=proton.json"} {
+		if _, err := parseOutputs(input); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestParseProxyListeners(t *testing.T) {
+	listeners, err := parseProxyListeners("socks5, HTTP=0.0.0.0:3128")
+	if err != nil {
+		t.Fatalf("parseProxyListeners failed: %v", err)
+	}
+	want := []ProxyListener{{Type: "socks5", Address: "127.0.0.1:1080"}, {Type: "http", Address: "0.0.0.0:3128"}}
+	if len(listeners) != len(want) || listeners[0] != want[0] || listeners[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, listeners)
+	}
+
+	for _, input := range []string{"socks4", "http=3128", "socks5,socks5=127.0.0.1:1081"} {
+		if _, err := parseProxyListeners(input); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestValidateQR(t *testing.T) {
+	for _, qr := range []string{"", "terminal", "phone.png", "out/phone.SVG"} {
+		if err := validateQR(qr); err != nil {
+			t.Errorf("Expected %q to be accepted, got %v", qr, err)
+		}
+	}
+	for _, qr := range []string{"phone.jpg", "term"} {
+		if err := validateQR(qr); err == nil {
+			t.Errorf("Expected %q to be rejected", qr)
+		}
+	}
+}
+
+func TestDeviceConfigsOutputs(t *testing.T) {
+	cfg := &Config{
+		OutputFile: "proton.conf",
+		Outputs:    []Output{{Format: "wg-quick"}, {Format: "json", Path: "out/proton.json"}},
+		Devices:    []string{"laptop", "phone"},
+	}
+
+	configs := cfg.DeviceConfigs()
+	if configs[1].OutputFile != "proton-phone.conf" {
+		t.Errorf("Expected proton-phone.conf, got %s", configs[1].OutputFile)
+	}
+	if configs[1].Outputs[0].Path != "" || configs[1].Outputs[1].Path != "out/proton-phone.json" {
+		t.Errorf("Unexpected device outputs: %v", configs[1].Outputs)
+	}
+	if cfg.Outputs[1].Path != "out/proton.json" {
+		t.Error("Expected the original outputs to be unchanged")
+	}
+	// Interface names get the device suffix, shortened to the kernel's limit when needed
+	cfg.InterfaceName = "wg"
+	configs = cfg.DeviceConfigs()
+	if name := configs[1].DeviceInterfaceName(); name != "wg-phone" {
+		t.Errorf("Expected wg-phone, got %s", name)
+	}
+	if name := cfg.DeviceInterfaceName(); name != "wg" {
+		t.Errorf("Expected wg without -devices, got %s", name)
+	}
+	cfg.InterfaceName = "protonvpn"
+	cfg.Devices = []string{"laptop-1", "laptop-2"}
+	configs = cfg.DeviceConfigs()
+	first, second := configs[0].DeviceInterfaceName(), configs[1].DeviceInterfaceName()
+	if first == second || len(first) > constants.MaxInterfaceNameLength || !strings.HasPrefix(first, "protonvpn-") || !validation.IsValidInterfaceName(first) {
+		t.Errorf("Expected distinct valid shortened names, got %s and %s", first, second)
+	}
+}
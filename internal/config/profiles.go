@@ -0,0 +1,235 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"protonvpn-wg-confgen/pkg/validation"
+)
+
+// ProfileSet is the content of a -profiles file
+type ProfileSet struct {
+	Profiles     []Profile    `json:"profiles"`
+	CertPolicies []CertPolicy `json:"cert_policies,omitempty"` // Custom or overridden certificate policies
+}
+
+// Profile is a named set of server selection settings that is active during a time window.
+// Unset fields keep the value from the command line.
+type Profile struct {
+	Name string `json:"name"`
+
+	// Schedule (local time). A profile without start/end is always active.
+	// Windows may wrap around midnight (e.g., 22:00-07:00); days refer to the day the window starts.
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start,omitempty"`
+	End   string   `json:"end,omitempty"`
+
+	// Server selection
+	Countries     []string `json:"countries,omitempty"`
+	P2POnly       *bool    `json:"p2p_only,omitempty"`
+	SecureCore    *bool    `json:"secure_core,omitempty"`
+	FreeOnly      *bool    `json:"free_only,omitempty"`
+	StreamingOnly *bool    `json:"streaming_only,omitempty"`
+	Server        string   `json:"server,omitempty"`
+	PhysicalID    string   `json:"physical_id,omitempty"`
+
+	// Certificate policy (built-in laptop or router, or one from cert_policies)
+	CertPolicy string `json:"cert_policy,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// LoadProfiles reads and validates a profiles file
+func LoadProfiles(path string) (*ProfileSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+
+	var set ProfileSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles file: %w", err)
+	}
+
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+// validate checks profile names, schedules and country codes
+func (ps *ProfileSet) validate() error {
+	if len(ps.Profiles) == 0 {
+		return fmt.Errorf("profiles file defines no profiles")
+	}
+
+	for i := range ps.CertPolicies {
+		if err := ps.CertPolicies[i].validate(); err != nil {
+			return err
+		}
+	}
+
+	names := make(map[string]bool)
+	for i := range ps.Profiles {
+		p := &ps.Profiles[i]
+		if p.Name == "" {
+			return fmt.Errorf("profile #%d has no name", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate profile name: %s", p.Name)
+		}
+		names[p.Name] = true
+
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+		if p.CertPolicy != "" {
+			if _, err := findCertPolicy(p.CertPolicy, ps); err != nil {
+				return fmt.Errorf("profile %s: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Profile) validate() error {
+	if (p.Start == "") != (p.End == "") {
+		return fmt.Errorf("start and end must be set together")
+	}
+	if p.Start != "" {
+		start, err := parseClock(p.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(p.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("start and end are both %s; omit them for a profile active all day", p.Start)
+		}
+	}
+
+	for _, day := range p.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid day: %s (expected mon, tue, wed, thu, fri, sat or sun)", day)
+		}
+	}
+
+	for i, country := range p.Countries {
+		p.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+		if !validation.IsValidCountryCode(p.Countries[i]) {
+			return fmt.Errorf("invalid country code: %s", country)
+		}
+	}
+	return nil
+}
+
+// Find returns the profile with the given name
+func (ps *ProfileSet) Find(name string) (*Profile, error) {
+	for i := range ps.Profiles {
+		if ps.Profiles[i].Name == name {
+			return &ps.Profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("profile not found: %s", name)
+}
+
+// Active returns the first profile whose schedule contains the given time, or nil if none does
+func (ps *ProfileSet) Active(now time.Time) *Profile {
+	for i := range ps.Profiles {
+		if ps.Profiles[i].IsActive(now) {
+			return &ps.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// IsActive reports whether the profile's schedule contains the given time
+func (p *Profile) IsActive(now time.Time) bool {
+	if p.Start == "" {
+		return p.isActiveOn(now.Weekday())
+	}
+
+	// Errors are caught by validate when loading
+	start, _ := parseClock(p.Start)
+	end, _ := parseClock(p.End)
+	minute := now.Hour()*60 + now.Minute()
+
+	if start <= end {
+		return minute >= start && minute < end && p.isActiveOn(now.Weekday())
+	}
+
+	// Window wraps around midnight: the early part belongs to the previous day's window
+	if minute >= start {
+		return p.isActiveOn(now.Weekday())
+	}
+	if minute < end {
+		return p.isActiveOn((now.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// isActiveOn checks the profile's day filter
+func (p *Profile) isActiveOn(day time.Weekday) bool {
+	if len(p.Days) == 0 {
+		return true
+	}
+	for _, d := range p.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply copies the profile's selection settings onto the configuration
+func (p *Profile) Apply(cfg *Config) {
+	cfg.ActiveProfile = p.Name
+
+	if len(p.Countries) > 0 {
+		cfg.Countries = p.Countries
+	}
+	if p.P2POnly != nil {
+		cfg.P2PServersOnly = *p.P2POnly
+	}
+	if p.SecureCore != nil {
+		cfg.SecureCoreOnly = *p.SecureCore
+	}
+	if p.FreeOnly != nil {
+		cfg.FreeOnly = *p.FreeOnly
+	}
+	if p.StreamingOnly != nil {
+		cfg.StreamingOnly = *p.StreamingOnly
+	}
+	if p.Server != "" {
+		cfg.ServerName = p.Server
+	}
+	if p.PhysicalID != "" {
+		cfg.PhysicalServerID = p.PhysicalID
+	}
+	if p.CertPolicy != "" {
+		cfg.CertPolicy = p.CertPolicy
+	}
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time: %s (expected HH:MM)", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testProfiles = `{
+  "profiles": [
+    {"name": "night-p2p", "start": "22:00", "end": "07:00", "days": ["fri"], "countries": ["nl"], "p2p_only": true},
+    {"name": "evening-streaming", "start": "18:00", "end": "22:00", "countries": ["GB"], "p2p_only": false, "streaming_only": true},
+    {"name": "default", "countries": ["CH"]}
+  ]
+}`
+
+func writeTestProfiles(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write profiles: %v", err)
+	}
+	return path
+}
+
+func TestActiveProfile(t *testing.T) {
+	profiles, err := LoadProfiles(writeTestProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatalf("LoadProfiles failed: %v", err)
+	}
+
+	// 2026-10-16 is a Friday
+	tests := []struct {
+		when string
+		want string
+	}{
+		{"2026-10-16 23:30", "night-p2p"},
+		{"2026-10-17 03:00", "night-p2p"}, // Saturday morning belongs to Friday's window
+		{"2026-10-18 03:00", "default"},   // Sunday morning belongs to Saturday's window, which is not scheduled
+		{"2026-10-16 19:00", "evening-streaming"},
+		{"2026-10-16 22:00", "night-p2p"},
+		{"2026-10-16 12:00", "default"},
+	}
+
+	for _, tt := range tests {
+		now, err := time.ParseInLocation("2006-01-02 15:04", tt.when, time.Local)
+		if err != nil {
+			t.Fatalf("bad test time: %v", err)
+		}
+
+		profile := profiles.Active(now)
+		if profile == nil || profile.Name != tt.want {
+			t.Errorf("At %s: expected profile %s, got %+v", tt.when, tt.want, profile)
+		}
+	}
+}
+
+func TestProfileApply(t *testing.T) {
+	profiles, err := LoadProfiles(writeTestProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatalf("LoadProfiles failed: %v", err)
+	}
+
+	profile, err := profiles.Find("evening-streaming")
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	cfg := &Config{Countries: []string{"US"}, P2PServersOnly: true, SecureCoreOnly: true}
+	profile.Apply(cfg)
+
+	if cfg.ActiveProfile != "evening-streaming" {
+		t.Errorf("Expected active profile to be recorded, got %q", cfg.ActiveProfile)
+	}
+	if len(cfg.Countries) != 1 || cfg.Countries[0] != "GB" {
+		t.Errorf("Expected countries [GB], got %v", cfg.Countries)
+	}
+	if cfg.P2PServersOnly || !cfg.StreamingOnly {
+		t.Errorf("Expected P2P off and Streaming on, got p2p=%v streaming=%v", cfg.P2PServersOnly, cfg.StreamingOnly)
+	}
+	// Fields not set by the profile keep their flag value
+	if !cfg.SecureCoreOnly {
+		t.Error("Expected SecureCoreOnly to be preserved")
+	}
+}
+
+func TestLoadProfilesValidation(t *testing.T) {
+	invalid := []string{
+		`{"profiles": []}`,
+		`{"profiles": [{"start": "10:00", "end": "11:00"}]}`,
+		`{"profiles": [{"name": "a"}, {"name": "a"}]}`,
+		`{"profiles": [{"name": "a", "start": "10:00"}]}`,
+		`{"profiles": [{"name": "a", "start": "25:00", "end": "11:00"}]}`,
+		`{"profiles": [{"name": "a", "start": "10:00", "end": "10:00"}]}`,
+		`{"profiles": [{"name": "a", "days": ["someday"]}]}`,
+		`{"profiles": [{"name": "a", "countries": ["USA"]}]}`,
+	}
+
+	for _, content := range invalid {
+		if _, err := LoadProfiles(writeTestProfiles(t, content)); err == nil {
+			t.Errorf("Expected validation error for %s", content)
+		}
+	}
+}
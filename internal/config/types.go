@@ -1,6 +1,13 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
 
 // Config holds all configuration options
 type Config struct {
@@ -13,22 +20,67 @@ type Config struct {
 	P2PServersOnly bool
 	SecureCoreOnly bool
 	FreeOnly       bool
-	// New flag: list all servers (bypass country filter and just print)
-    ListAllServers bool `json:"-"`
+	StreamingOnly  bool
+	// List all servers (bypass country filter and just print)
+	ListAllServers bool `json:"-"`
+
+	// Server pinning (bypasses ranking)
+	ServerName       string
+	PhysicalServerID string
+
+	// Selection profiles
+	ProfilesFile  string
+	ProfileName   string
+	ActiveProfile string // Name of the profile applied to this configuration, if any
 
 	// Output configuration
 	OutputFile       string
+	Outputs          []Output // Output formats and their files, from -format
+	InterfaceName    string   // Interface name for formats that create the interface
+	NMConnectionDir  string   // Directory for NetworkManager keyfiles, e.g. /etc/NetworkManager/system-connections
+	NMAutoconnect    bool
+	FirewallZone     bool   // Add a firewall zone to router formats
+	SecretKey        string // Key of the configuration in the Kubernetes Secret
+	GroupSize        int    // Servers in the selectable group of proxy formats
+	ProxyListeners   []ProxyListener
+	QR               string // "terminal" or a .png or .svg file for the wg-quick configuration as a QR code
+	QRStrip          bool   // Leave the metadata comments out of the QR code
 	ClientPrivateKey string
 	DeviceName       string
 
+	// Multi-device generation
+	Devices         []string
+	Device          string // Device of -devices this configuration was derived from
+	AntiAffinity    string
+	ConnectionLimit string
+
 	// Network configuration
 	DNSServers        []string
 	AllowedIPs        []string
 	EnableAccelerator bool
 	EnableIPv6        bool
 
+	// Certificate features
+	NetShieldLevel int
+	ModerateNAT    bool
+	PortForwarding bool
+	Bouncing       bool
+
 	// Certificate configuration
-	Duration string
+	Duration   string
+	CertMode   string // persistent or session
+	CertPolicy string // Name of the certificate policy that set the mode and duration
+
+	// Key persistence
+	NoKeyStore  bool
+	RotateKey   bool
+	Renew       bool
+	KeyStoreDir string
+	KeyProfile  string
+
+	// Key import (instead of generating a key)
+	PrivateKeyFile string // Path, or "-" for stdin
+	PrivateKeyEnv  string // Name of the environment variable holding the key
 
 	// Session management
 	ClearSession    bool
@@ -41,6 +93,19 @@ type Config struct {
 	Debug  bool
 }
 
+// Output is an output format and the file it is written to.
+// An empty path is derived from OutputFile by the wireguard package.
+type Output struct {
+	Format string
+	Path   string
+}
+
+// ProxyListener is a local proxy listener of the wireproxy output
+type ProxyListener struct {
+	Type    string // socks5 or http
+	Address string // Bind address (host:port)
+}
+
 // ValidateCredentials checks if we have the required credentials
 func (c *Config) ValidateCredentials() error {
 	if c.Username == "" {
@@ -48,3 +113,93 @@ func (c *Config) ValidateCredentials() error {
 	}
 	return nil
 }
+
+// HasPinnedServer reports whether a specific logical or physical server was requested
+func (c *Config) HasPinnedServer() bool {
+	return c.ServerName != "" || c.PhysicalServerID != ""
+}
+
+// DeviceConfigs returns one configuration per device requested with -devices.
+// Each copy gets its own device name and an output file derived from OutputFile
+// (e.g., protonvpn.conf becomes protonvpn-laptop.conf). Without -devices the
+// configuration itself is returned.
+func (c *Config) DeviceConfigs() []*Config {
+	if len(c.Devices) == 0 {
+		return []*Config{c}
+	}
+
+	configs := make([]*Config, 0, len(c.Devices))
+	for _, device := range c.Devices {
+		deviceCfg := *c
+		deviceCfg.DeviceName = device
+		deviceCfg.Device = device
+		deviceCfg.OutputFile = deviceFileName(c.OutputFile, device)
+		deviceCfg.Outputs = make([]Output, len(c.Outputs))
+		for i, output := range c.Outputs {
+			deviceCfg.Outputs[i] = output
+			if output.Path != "" {
+				deviceCfg.Outputs[i].Path = deviceFileName(output.Path, device)
+			}
+		}
+		if c.QR != "" && c.QR != constants.QRTerminal {
+			deviceCfg.QR = deviceFileName(c.QR, device)
+		}
+		if c.KeyProfile != "" {
+			deviceCfg.KeyProfile = c.KeyProfile + "-" + device
+		}
+		configs = append(configs, &deviceCfg)
+	}
+	return configs
+}
+
+// deviceFileName derives a per-device file name, e.g. protonvpn.conf becomes protonvpn-laptop.conf
+func deviceFileName(path, device string) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), sanitizeFileName(device), ext)
+}
+
+// DeviceInterfaceName returns -interface with the device suffix of -devices (e.g., protonvpn-phone),
+// for outputs imported side by side on one host. Names longer than an interface name allows
+// are shortened and end in a hash of the device, so they stay distinct.
+func (c *Config) DeviceInterfaceName() string {
+	name := c.InterfaceName
+	if name == "" {
+		name = constants.DefaultInterfaceName
+	}
+	if c.Device == "" {
+		return name
+	}
+
+	name += "-" + sanitizeFileName(c.Device)
+	if len(name) > constants.MaxInterfaceNameLength {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(c.Device))
+		prefix := strings.TrimRight(name[:constants.MaxInterfaceNameLength-5], "-_.")
+		name = fmt.Sprintf("%s-%04x", prefix, hash.Sum32()&0xffff)
+	}
+	return name
+}
+
+// KeyProfileName returns the key store profile: -key-profile, else the device name, else "default"
+func (c *Config) KeyProfileName() string {
+	switch {
+	case c.KeyProfile != "":
+		return c.KeyProfile
+	case c.DeviceName != "":
+		return c.DeviceName
+	default:
+		return constants.DefaultKeyProfile
+	}
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+}
@@ -3,12 +3,16 @@ package constants
 
 // API endpoints
 const (
-	DefaultAPIURL   = "https://vpn-api.proton.me"
-	AuthInfoPath    = "/core/v4/auth/info"
-	AuthPath        = "/core/v4/auth"
-	RefreshPath     = "/auth/refresh"
-	CertificatePath = "/vpn/v1/certificate"
-	LogicalsPath    = "/vpn/v1/logicals"
+	DefaultAPIURL         = "https://vpn-api.proton.me"
+	AuthInfoPath          = "/core/v4/auth/info"
+	AuthPath              = "/core/v4/auth"
+	RefreshPath           = "/auth/refresh"
+	CertificatePath       = "/vpn/v1/certificate"
+	CertificateListPath   = "/vpn/v1/certificate/all"
+	CertificateDeletePath = "/vpn/v1/certificate/delete"
+	LogicalsPath          = "/vpn/v1/logicals"
+	AccountPath           = "/vpn/v2"
+	SessionsPath          = "/vpn/v1/sessions"
 )
 
 // API version headers - can be overridden at build time via ldflags:
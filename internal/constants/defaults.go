@@ -3,12 +3,84 @@ package constants
 // Certificate defaults
 const (
 	DefaultCertDuration = "365d"
-	MaxCertDuration     = 365 // days
-	CertMode            = "persistent"
+	MaxCertDuration     = 365                // days
+	CertMode            = CertModePersistent // Default certificate mode
 	PublicKeyMode       = "EC"
+	DeviceNamePrefix    = "WireGuard-" // Prefix of auto-generated device names
+)
+
+// Output defaults
+const (
+	DefaultOutputFormat = "wg-quick"
+	DefaultSecretKey    = "wg0.conf" // Key of the configuration in the kubernetes output
+)
+
+// QRTerminal is the -qr value that prints the QR code instead of writing an image
+const QRTerminal = "terminal"
+
+// Listeners of the wireproxy output
+const (
+	ProxyListenerSOCKS5   = "socks5"
+	ProxyListenerHTTP     = "http"
+	DefaultProxyListeners = ProxyListenerSOCKS5
+	DefaultSOCKS5Address  = "127.0.0.1:1080"
+	DefaultHTTPAddress    = "127.0.0.1:8080"
+)
+
+// Certificate modes
+const (
+	CertModePersistent = "persistent" // Listed as a device in the dashboard, up to 365 days
+	CertModeSession    = "session"    // Short-lived, not listed as a device
+)
+
+// Built-in certificate policies
+const (
+	CertPolicyLaptop = "laptop" // Session certificates for 24h (at most 7d)
+	CertPolicyRouter = "router" // Persistent certificates for 365d
+)
+
+// NetShield levels requested in VPN certificates
+const (
+	NetShieldOff        = 0 // No blocking
+	NetShieldMalware    = 1 // Block malware
+	NetShieldAdsMalware = 2 // Block ads, trackers and malware
 )
 
 // Server selection defaults
 const (
 	DefaultP2POnly = true
 )
+
+// Anti-affinity modes for multi-device generation
+const (
+	AntiAffinityNone   = "none"   // Devices may share the best server
+	AntiAffinityServer = "server" // Each device gets a distinct logical server
+	AntiAffinityCity   = "city"   // Each device gets a server in a distinct city
+)
+
+// Connection limit handling modes
+const (
+	ConnectionLimitWarn   = "warn"   // Print a warning when the plan's connection limit would be exceeded
+	ConnectionLimitRefuse = "refuse" // Fail before registering any device
+	ConnectionLimitIgnore = "ignore" // Skip the check
+)
+
+// Expiry monitoring defaults
+const (
+	DefaultExpiryThreshold = "30d"
+)
+
+// Exit codes
+const (
+	ExitCodeError     = 1 // Generation failed
+	ExitCodeUnchanged = 3 // -renew: no configuration was rewritten
+	ExitCodeExpiring  = 4 // check-expiry: a certificate expires within the threshold
+)
+
+// Port forwarding defaults (NAT-PMP against the tunnel gateway)
+const (
+	PortForwardGateway      = "10.2.0.1"
+	PortForwardLifetime     = 60 // seconds
+	PortForwardInterval     = 45 // seconds
+	PortForwardExternalPort = 1  // Suggested external port; the gateway assigns its own
+)
@@ -7,3 +7,14 @@ const (
 	SessionRefreshDays   = 7       // Refresh when less than 7 days remain
 	SessionExpirySeconds = 2592000 // 30 days in seconds (from API)
 )
+
+// Key store defaults
+const (
+	KeyStoreDirName         = ".protonvpn-keys"
+	KeyStoreDirMode         = 0o700 // Owner only
+	KeyStoreFileMode        = 0o600 // Read/write for owner only
+	KeyPassphraseEnv        = "PROTONVPN_KEY_PASSPHRASE"
+	DefaultKeyProfile       = "default"
+	CertRenewBeforeDays     = 7      // Re-register when the certificate expires in less than 7 days
+	KeyEncryptionIterations = 600000 // PBKDF2-SHA256 iterations for encrypted keys
+)
@@ -15,3 +15,11 @@ const (
 	DefaultDNSIPv6        = "2a07:b944::2:1"
 	DefaultAllowedIPsIPv6 = "::/0"
 )
+
+// Interface and policy routing defaults for formats that configure the interface themselves
+const (
+	DefaultInterfaceName   = "protonvpn"
+	MaxInterfaceNameLength = 15    // Linux IFNAMSIZ without the terminating NUL
+	RoutingTable           = 51820 // Table for full-tunnel routes, as used by wg-quick
+	FirewallMark           = 51820 // Marks the tunnel's own packets so they bypass RoutingTable
+)
@@ -0,0 +1,76 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// EncryptedKey is a private key encrypted with AES-256-GCM under a PBKDF2-SHA256 derived key
+type EncryptedKey struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Iterations int    `json:"iterations"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptKey encrypts a key seed with the passphrase
+func encryptKey(seed []byte, passphrase string) (*EncryptedKey, error) {
+	encrypted := &EncryptedKey{
+		Salt:       make([]byte, 16),
+		Iterations: constants.KeyEncryptionIterations,
+	}
+	if _, err := rand.Read(encrypted.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, encrypted.Salt, encrypted.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(encrypted.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	encrypted.Ciphertext = aead.Seal(nil, encrypted.Nonce, seed, nil)
+	return encrypted, nil
+}
+
+// decryptKey decrypts a key seed with the passphrase
+func decryptKey(encrypted *EncryptedKey, passphrase string) ([]byte, error) {
+	aead, err := newAEAD(passphrase, encrypted.Salt, encrypted.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length in stored key")
+	}
+
+	seed, err := aead.Open(nil, encrypted.Nonce, encrypted.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt stored key (wrong passphrase?)")
+	}
+	return seed, nil
+}
+
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,280 @@
+// Package keystore persists device key pairs and certificate metadata across runs.
+package keystore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/pkg/keys"
+)
+
+// Store handles persistent key storage, one file per user and profile
+type Store struct {
+	dir        string
+	passphrase string
+}
+
+// NewStore creates a key store in dir (~/.protonvpn-keys if empty).
+// Keys are encrypted at rest when passphrase is not empty.
+func NewStore(dir, passphrase string) *Store {
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			// Fallback to current directory
+			homeDir = "."
+		}
+		dir = filepath.Join(homeDir, constants.KeyStoreDirName)
+	}
+
+	return &Store{
+		dir:        dir,
+		passphrase: passphrase,
+	}
+}
+
+// Entry is a stored device identity
+type Entry struct {
+	Profile      string          `json:"profile"`
+	Username     string          `json:"username"`
+	PrivateKey   string          `json:"private_key,omitempty"` // Base64 Ed25519 seed (unencrypted stores)
+	EncryptedKey *EncryptedKey   `json:"encrypted_key,omitempty"`
+	Certificate  CertificateInfo `json:"certificate"`
+	Server       ServerInfo      `json:"server"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// CertificateInfo holds the metadata of the certificate registered for the key
+type CertificateInfo struct {
+	DeviceName     string                  `json:"device_name"`
+	SerialNumber   string                  `json:"serial_number"`
+	Fingerprint    string                  `json:"client_key_fingerprint,omitempty"`
+	Mode           string                  `json:"mode,omitempty"`
+	Certificate    string                  `json:"certificate,omitempty"` // PEM
+	Features       api.CertificateFeatures `json:"features"`              // Granted by the API
+	Requested      api.CertificateFeatures `json:"requested_features"`    // Requested at registration
+	IssuedAt       int64                   `json:"issued_at,omitempty"`
+	RefreshTime    int64                   `json:"refresh_time"`
+	ExpirationTime int64                   `json:"expiration_time"`
+}
+
+// ServerInfo records the server the device's WireGuard configuration was last written for
+type ServerInfo struct {
+	Name             string   `json:"name,omitempty"`
+	PhysicalServerID string   `json:"physical_server_id,omitempty"`
+	PublicKey        string   `json:"public_key,omitempty"`
+	Endpoint         string   `json:"endpoint,omitempty"`
+	Domain           string   `json:"domain,omitempty"`  // TLS server name of the local agent
+	Outputs          []string `json:"outputs,omitempty"` // Files written for the -format outputs
+}
+
+// SetCertificate records the metadata of a newly registered certificate and the features it was requested with
+func (e *Entry) SetCertificate(vpnInfo *api.VPNInfo, requested api.CertificateFeatures) {
+	e.Certificate = NewCertificateInfo(vpnInfo, requested)
+}
+
+// NewCertificateInfo returns the metadata of a registered certificate
+func NewCertificateInfo(vpnInfo *api.VPNInfo, requested api.CertificateFeatures) CertificateInfo {
+	return CertificateInfo{
+		DeviceName:     vpnInfo.DeviceName,
+		SerialNumber:   vpnInfo.SerialNumber,
+		Fingerprint:    vpnInfo.ClientKeyFingerprint,
+		Mode:           vpnInfo.Mode,
+		Certificate:    vpnInfo.Certificate,
+		Features:       vpnInfo.Features,
+		Requested:      requested,
+		IssuedAt:       time.Now().Unix(),
+		RefreshTime:    vpnInfo.RefreshTime,
+		ExpirationTime: vpnInfo.ExpirationTime,
+	}
+}
+
+// SetServer records the server a configuration was written for
+func (e *Entry) SetServer(server *api.LogicalServer, physicalServer *api.PhysicalServer, outputs []string) {
+	e.Server = ServerInfo{
+		Name:             server.Name,
+		PhysicalServerID: physicalServer.ID,
+		PublicKey:        physicalServer.X25519PublicKey,
+		Endpoint:         physicalServer.EntryIP,
+		Domain:           physicalServer.Domain,
+		Outputs:          outputs,
+	}
+}
+
+// Matches reports whether configurations for the given server and output files
+// would connect to the same endpoint as the recorded ones
+func (si *ServerInfo) Matches(physicalServer *api.PhysicalServer, outputs []string) bool {
+	return si.PhysicalServerID == physicalServer.ID &&
+		si.PublicKey == physicalServer.X25519PublicKey &&
+		si.Endpoint == physicalServer.EntryIP &&
+		slices.Equal(si.Outputs, outputs)
+}
+
+// RefreshAt returns when the certificate should be refreshed
+func (c *CertificateInfo) RefreshAt() time.Time {
+	return time.Unix(c.RefreshTime, 0)
+}
+
+// ExpiresAt returns when the certificate expires
+func (c *CertificateInfo) ExpiresAt() time.Time {
+	return time.Unix(c.ExpirationTime, 0)
+}
+
+// NeedsRenewal reports whether the certificate has passed its refresh time or expires
+// within constants.CertRenewBeforeDays. Short-lived certificates are renewed within
+// the last quarter of their lifetime instead.
+func (c *CertificateInfo) NeedsRenewal(now time.Time) bool {
+	if c.RefreshTime == 0 || c.ExpirationTime == 0 {
+		return true
+	}
+	renewBefore := time.Duration(constants.CertRenewBeforeDays) * 24 * time.Hour
+	if c.IssuedAt > 0 {
+		if quarter := c.ExpiresAt().Sub(time.Unix(c.IssuedAt, 0)) / 4; quarter < renewBefore {
+			renewBefore = quarter
+		}
+	}
+	return !now.Before(c.RefreshAt()) || c.ExpiresAt().Sub(now) < renewBefore
+}
+
+// Load retrieves the entry and key pair for a user and profile.
+// It returns nil values without error when nothing is stored.
+func (s *Store) Load(username, profile string) (*Entry, *keys.KeyPair, error) {
+	data, err := os.ReadFile(s.path(username, profile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal key file: %w", err)
+	}
+
+	seed, err := s.decodeKey(&entry)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPair, err := keys.FromSeed(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &entry, keyPair, nil
+}
+
+// Save stores the entry with the given key pair, encrypting the key if a passphrase is set
+func (s *Store) Save(entry *Entry, keyPair *keys.KeyPair) error {
+	now := time.Now()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = now
+	}
+	entry.UpdatedAt = now
+
+	entry.PrivateKey = ""
+	entry.EncryptedKey = nil
+	if s.passphrase != "" {
+		encrypted, err := encryptKey(keyPair.Seed(), s.passphrase)
+		if err != nil {
+			return err
+		}
+		entry.EncryptedKey = encrypted
+	} else {
+		entry.PrivateKey = base64.StdEncoding.EncodeToString(keyPair.Seed())
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key file: %w", err)
+	}
+
+	if err := os.MkdirAll(s.dir, constants.KeyStoreDirMode); err != nil {
+		return fmt.Errorf("failed to create key store directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.path(entry.Username, entry.Profile), data, constants.KeyStoreFileMode); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the metadata of all stored entries without decoding their keys
+func (s *Store) List() ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal key file %s: %w", filepath.Base(path), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Delete removes the stored entry for a user and profile
+func (s *Store) Delete(username, profile string) error {
+	err := os.Remove(s.path(username, profile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete key file: %w", err)
+	}
+	return nil
+}
+
+// GetPath returns the key file path for a user and profile
+func (s *Store) GetPath(username, profile string) string {
+	return s.path(username, profile)
+}
+
+func (s *Store) path(username, profile string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%s.json", sanitize(username), sanitize(profile)))
+}
+
+// decodeKey returns the Ed25519 seed of an entry, decrypting it if needed
+func (s *Store) decodeKey(entry *Entry) ([]byte, error) {
+	if entry.EncryptedKey != nil {
+		if s.passphrase == "" {
+			return nil, fmt.Errorf("key for profile %s is encrypted, set %s to decrypt it", entry.Profile, constants.KeyPassphraseEnv)
+		}
+		return decryptKey(entry.EncryptedKey, s.passphrase)
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(entry.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stored key: %w", err)
+	}
+	return seed, nil
+}
+
+// sanitize makes a user or profile name safe to use in a file name
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
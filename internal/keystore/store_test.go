@@ -0,0 +1,110 @@
+package keystore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/pkg/keys"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse"} {
+		store := NewStore(t.TempDir(), passphrase)
+
+		keyPair, err := keys.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		entry := &Entry{Profile: "router", Username: "alice"}
+		if err := store.Save(entry, keyPair); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		info, err := os.Stat(store.GetPath("alice", "router"))
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected key file mode 0600, got %o", info.Mode().Perm())
+		}
+
+		loaded, loadedKey, err := store.Load("alice", "router")
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if loaded == nil || loadedKey.ToX25519Base64() != keyPair.ToX25519Base64() {
+			t.Errorf("Loaded key does not match saved key (passphrase %q)", passphrase)
+		}
+		if (passphrase != "") != (loaded.EncryptedKey != nil) || (passphrase != "") == (loaded.PrivateKey != "") {
+			t.Errorf("Unexpected key encoding for passphrase %q", passphrase)
+		}
+	}
+}
+
+func TestStoreWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+
+	keyPair, err := keys.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := NewStore(dir, "secret").Save(&Entry{Profile: "p", Username: "u"}, keyPair); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if _, _, err := NewStore(dir, "wrong").Load("u", "p"); err == nil {
+		t.Error("Expected error with wrong passphrase")
+	}
+	if _, _, err := NewStore(dir, "").Load("u", "p"); err == nil {
+		t.Error("Expected error without passphrase")
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		cert CertificateInfo
+		want bool
+	}{
+		{"unknown", CertificateInfo{}, true},
+		{"fresh", CertificateInfo{RefreshTime: now.Add(24 * time.Hour).Unix(), ExpirationTime: now.Add(300 * 24 * time.Hour).Unix()}, false},
+		{"refresh passed", CertificateInfo{RefreshTime: now.Add(-time.Hour).Unix(), ExpirationTime: now.Add(300 * 24 * time.Hour).Unix()}, true},
+		{"expiring", CertificateInfo{RefreshTime: now.Add(24 * time.Hour).Unix(), ExpirationTime: now.Add(3 * 24 * time.Hour).Unix()}, true},
+		{"short-lived", CertificateInfo{IssuedAt: now.Add(-time.Hour).Unix(), RefreshTime: now.Add(12 * time.Hour).Unix(), ExpirationTime: now.Add(23 * time.Hour).Unix()}, false},
+		{"short-lived ending", CertificateInfo{IssuedAt: now.Add(-20 * time.Hour).Unix(), RefreshTime: now.Add(time.Hour).Unix(), ExpirationTime: now.Add(4 * time.Hour).Unix()}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cert.NeedsRenewal(now); got != tt.want {
+			t.Errorf("%s: expected NeedsRenewal=%v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestServerInfoMatches(t *testing.T) {
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{ID: "p1", X25519PublicKey: "pub", EntryIP: "10.0.0.1"}
+
+	var entry Entry
+	entry.SetServer(server, physicalServer, []string{"ch.conf"})
+
+	if !entry.Server.Matches(physicalServer, []string{"ch.conf"}) {
+		t.Error("Expected recorded server to match")
+	}
+	if entry.Server.Matches(physicalServer, []string{"other.conf"}) {
+		t.Error("Expected a different output file not to match")
+	}
+	if entry.Server.Matches(physicalServer, []string{"ch.conf", "ch.json"}) {
+		t.Error("Expected an added output format not to match")
+	}
+
+	moved := *physicalServer
+	moved.EntryIP = "10.0.0.2"
+	if entry.Server.Matches(&moved, []string{"ch.conf"}) {
+		t.Error("Expected a changed endpoint not to match")
+	}
+}
@@ -0,0 +1,111 @@
+package vpn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// certificatePageSize is the number of certificates requested per page
+const certificatePageSize = 50
+
+// ListCertificates fetches all persistent certificates (devices) of the account
+func (c *Client) ListCertificates() ([]api.VPNInfo, error) {
+	var certificates []api.VPNInfo
+
+	for offset := 0; ; offset += certificatePageSize {
+		query := url.Values{}
+		query.Set("Mode", constants.CertMode)
+		query.Set("Offset", strconv.Itoa(offset))
+		query.Set("Limit", strconv.Itoa(certificatePageSize))
+
+		var response api.CertificatesResponse
+		if err := c.getJSON(constants.CertificateListPath+"?"+query.Encode(), &response); err != nil {
+			return nil, err
+		}
+
+		if !constants.IsSuccessCode(response.Code) {
+			if response.Error != "" {
+				return nil, fmt.Errorf("certificate list error (code %d): %s", response.Code, response.Error)
+			}
+			return nil, fmt.Errorf("API returned error code: %d", response.Code)
+		}
+
+		certificates = append(certificates, response.Certificates...)
+		if len(response.Certificates) < certificatePageSize || (response.Total > 0 && len(certificates) >= response.Total) {
+			return certificates, nil
+		}
+	}
+}
+
+// DeleteCertificates revokes the certificates with the given client key fingerprints
+func (c *Client) DeleteCertificates(fingerprints []string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"ClientPublicKeyFingerprints": fingerprints,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.config.APIURL+constants.CertificateDeletePath, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Code  int    `json:"Code"`
+		Error string `json:"Error,omitempty"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+
+	if !constants.IsSuccessCode(response.Code) {
+		if response.Error != "" {
+			return fmt.Errorf("certificate delete error (code %d): %s", response.Code, response.Error)
+		}
+		return fmt.Errorf("API returned error code: %d", response.Code)
+	}
+
+	return nil
+}
+
+// CertificateCreatedAt returns when a certificate was created. It falls back to the
+// timestamp in device names generated by this tool (WireGuard-<user>-<unix>) and
+// returns the zero time when the creation time is unknown.
+func CertificateCreatedAt(cert *api.VPNInfo) time.Time {
+	if cert.CreationTime > 0 {
+		return time.Unix(cert.CreationTime, 0)
+	}
+
+	if strings.HasPrefix(cert.DeviceName, constants.DeviceNamePrefix) {
+		idx := strings.LastIndex(cert.DeviceName, "-")
+		if unix, err := strconv.ParseInt(cert.DeviceName[idx+1:], 10, 64); err == nil {
+			return time.Unix(unix, 0)
+		}
+	}
+
+	return time.Time{}
+}
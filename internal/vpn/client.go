@@ -12,9 +12,8 @@ import (
 	"protonvpn-wg-confgen/internal/api"
 	"protonvpn-wg-confgen/internal/config"
 	"protonvpn-wg-confgen/internal/constants"
+	"protonvpn-wg-confgen/pkg/keys"
 	"protonvpn-wg-confgen/pkg/timeutil"
-
-	"github.com/ProtonVPN/go-vpn-lib/ed25519"
 )
 
 // Client handles VPN operations
@@ -34,7 +33,7 @@ func NewClient(cfg *config.Config, session *api.Session) *Client {
 }
 
 // GetCertificate generates a VPN certificate
-func (c *Client) GetCertificate(keyPair *ed25519.KeyPair) (*api.VPNInfo, error) {
+func (c *Client) GetCertificate(keyPair *keys.KeyPair) (*api.VPNInfo, error) {
 	publicKeyPEM, err := keyPair.PublicKeyPKIXPem()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get public key PEM: %w", err)
@@ -43,7 +42,7 @@ func (c *Client) GetCertificate(keyPair *ed25519.KeyPair) (*api.VPNInfo, error)
 	// Use provided device name or generate one
 	deviceName := c.config.DeviceName
 	if deviceName == "" {
-		deviceName = fmt.Sprintf("WireGuard-%s-%d", c.config.Username, time.Now().Unix())
+		deviceName = fmt.Sprintf("%s%s-%d", constants.DeviceNamePrefix, c.config.Username, time.Now().Unix())
 	}
 
 	// Parse duration
@@ -53,19 +52,14 @@ func (c *Client) GetCertificate(keyPair *ed25519.KeyPair) (*api.VPNInfo, error)
 	}
 
 	// Build certificate request matching official ProtonVPN API format
-	// Feature keys from: python-proton-vpn-api-core/proton/vpn/session/fetcher.py
+	requested := RequestedFeatures(c.config)
 	certReq := map[string]interface{}{
 		"ClientPublicKey":     publicKeyPEM,
-		"ClientPublicKeyMode": "EC",
-		"Mode":                "persistent", // Create persistent configuration
+		"ClientPublicKeyMode": constants.PublicKeyMode,
+		"Mode":                c.certMode(),
 		"DeviceName":          deviceName,
 		"Duration":            durationStr,
-		"Features": map[string]interface{}{
-			"NetShieldLevel": 0,                          // NetShield disabled
-			"RandomNAT":      false,                      // Moderate NAT disabled
-			"PortForwarding": false,                      // Port forwarding disabled
-			"SplitTCP":       c.config.EnableAccelerator, // VPN Accelerator (called SplitTCP in API)
-		},
+		"Features":            NewFeaturesRequest(requested),
 	}
 
 	certJSON, err := json.Marshal(certReq)
@@ -107,28 +101,49 @@ func (c *Client) GetCertificate(keyPair *ed25519.KeyPair) (*api.VPNInfo, error)
 	return &vpnInfo, nil
 }
 
-// GetServers fetches the list of VPN servers
-func (c *Client) GetServers() ([]api.LogicalServer, error) {
-	req, err := http.NewRequest(http.MethodGet, c.config.APIURL+"/vpn/v1/logicals", http.NoBody)
-	if err != nil {
-		return nil, err
+// certMode returns the configured certificate mode, persistent by default
+func (c *Client) certMode() string {
+	if c.config.CertMode == "" {
+		return constants.CertMode
 	}
+	return c.config.CertMode
+}
 
-	c.setHeaders(req)
+// RequestedFeatures returns the certificate features requested by the configuration
+func RequestedFeatures(cfg *config.Config) api.CertificateFeatures {
+	return api.CertificateFeatures{
+		NetshieldLevel: cfg.NetShieldLevel,
+		ModerateNAT:    cfg.ModerateNAT,
+		PortForwarding: cfg.PortForwarding,
+		VPNAccelerator: cfg.EnableAccelerator,
+		Bouncing:       cfg.Bouncing,
+	}
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// NewFeaturesRequest converts requested features to the certificate request format.
+// RandomNAT is the strict (randomized) NAT that Moderate NAT turns off, like randomized-nat in the local agent.
+func NewFeaturesRequest(requested api.CertificateFeatures) api.CertificateFeaturesRequest {
+	return api.CertificateFeaturesRequest{
+		NetShieldLevel: requested.NetshieldLevel,
+		RandomNAT:      !requested.ModerateNAT,
+		PortForwarding: requested.PortForwarding,
+		SplitTCP:       requested.VPNAccelerator,
+		Bouncing:       requested.Bouncing,
 	}
-	defer func() { _ = resp.Body.Close() }()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// ValidateServerFeatures checks the requested certificate features against the selected server
+func ValidateServerFeatures(cfg *config.Config, server *api.LogicalServer) error {
+	if cfg.PortForwarding && server.Features&api.FeatureP2P == 0 {
+		return fmt.Errorf("-port-forwarding requires a P2P server, but %s does not support P2P", server.Name)
 	}
+	return nil
+}
 
+// GetServers fetches the list of VPN servers
+func (c *Client) GetServers() ([]api.LogicalServer, error) {
 	var response api.LogicalsResponse
-	if err := json.Unmarshal(body, &response); err != nil {
+	if err := c.getJSON(constants.LogicalsPath, &response); err != nil {
 		return nil, err
 	}
 
@@ -139,6 +154,46 @@ func (c *Client) GetServers() ([]api.LogicalServer, error) {
 	return response.LogicalServers, nil
 }
 
+// GetAccount fetches the VPN plan of the authenticated account
+func (c *Client) GetAccount() (*api.VPNAccount, error) {
+	var response api.VPNAccountResponse
+	if err := c.getJSON(constants.AccountPath, &response); err != nil {
+		return nil, err
+	}
+
+	if !constants.IsSuccessCode(response.Code) {
+		if response.Error != "" {
+			return nil, fmt.Errorf("VPN account error (code %d): %s", response.Code, response.Error)
+		}
+		return nil, fmt.Errorf("API returned error code: %d", response.Code)
+	}
+
+	return &response.VPN, nil
+}
+
+// getJSON performs an authenticated GET request and decodes the JSON response into out
+func (c *Client) getJSON(path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.config.APIURL+path, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, out)
+}
+
 func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.session.AccessToken))
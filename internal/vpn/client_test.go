@@ -0,0 +1,31 @@
+package vpn
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"protonvpn-wg-confgen/internal/api"
+)
+
+func TestNewFeaturesRequestNAT(t *testing.T) {
+	// RandomNAT is strict NAT: Moderate NAT turns it off, as randomized-nat does in the local agent
+	tests := []struct {
+		moderateNAT bool
+		want        string
+	}{
+		{false, `"RandomNAT":true`},
+		{true, `"RandomNAT":false`},
+	}
+
+	for _, tt := range tests {
+		request := NewFeaturesRequest(api.CertificateFeatures{ModerateNAT: tt.moderateNAT, VPNAccelerator: true})
+		data, err := json.Marshal(request)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if !strings.Contains(string(data), tt.want) || !strings.Contains(string(data), `"SplitTCP":true`) {
+			t.Errorf("ModerateNAT=%v: expected %s, got %s", tt.moderateNAT, tt.want, data)
+		}
+	}
+}
@@ -13,7 +13,8 @@ import (
 
 // ServerSelector handles server selection logic
 type ServerSelector struct {
-	config *config.Config
+	config  *config.Config
+	account *api.VPNAccount
 }
 
 // NewServerSelector creates a new server selector
@@ -21,8 +22,115 @@ func NewServerSelector(cfg *config.Config) *ServerSelector {
 	return &ServerSelector{config: cfg}
 }
 
+// SetAccount restricts selection to the tiers available to the account's VPN plan
+func (s *ServerSelector) SetAccount(account *api.VPNAccount) {
+	s.account = account
+}
+
+// ApplyAccountTier adapts the configuration to the account's VPN plan.
+// Free accounts are switched to Free tier servers automatically, and requests that
+// need a paid plan are refused before any device is registered.
+func ApplyAccountTier(cfg *config.Config, account *api.VPNAccount) error {
+	if !account.IsFree() {
+		return nil
+	}
+
+	if cfg.SecureCoreOnly {
+		return fmt.Errorf("-secure-core requires a paid plan, but your account (%s) only has access to Free tier servers", account.GetPlanName())
+	}
+	if cfg.StreamingOnly {
+		return fmt.Errorf("-streaming-only requires a paid plan, but your account (%s) only has access to Free tier servers", account.GetPlanName())
+	}
+	if cfg.NetShieldLevel > constants.NetShieldOff || cfg.ModerateNAT || cfg.PortForwarding {
+		return fmt.Errorf("-netshield, -moderate-nat and -port-forwarding require a paid plan, but your account (%s) is on the Free plan", account.GetPlanName())
+	}
+
+	if !cfg.FreeOnly {
+		fmt.Println("Free plan detected, selecting from Free tier servers only")
+		cfg.FreeOnly = true
+	}
+	return nil
+}
+
 // SelectBest selects the best server based on configuration
 func (s *ServerSelector) SelectBest(servers []api.LogicalServer) (*api.LogicalServer, error) {
+	ranked, err := s.rankServers(servers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ranked[0], nil
+}
+
+// SelectN selects one server for each of count devices, honouring the -distinct anti-affinity mode.
+// Allocation is done over the whole ranked list: the best server of every distinct
+// server or city is collected first, so no device is starved by an earlier greedy pick.
+func (s *ServerSelector) SelectN(servers []api.LogicalServer, count int) ([]*api.LogicalServer, error) {
+	ranked, err := s.rankServers(servers)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]*api.LogicalServer, 0, count)
+	if s.config.AntiAffinity == "" || s.config.AntiAffinity == constants.AntiAffinityNone {
+		for range count {
+			selected = append(selected, &ranked[0])
+		}
+		return selected, nil
+	}
+
+	seen := make(map[string]bool)
+	for i := range ranked {
+		key := s.affinityKey(&ranked[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		selected = append(selected, &ranked[i])
+		if len(selected) == count {
+			return selected, nil
+		}
+	}
+
+	return nil, fmt.Errorf("only %d servers with distinct %s available for %d devices",
+		len(selected), s.config.AntiAffinity, count)
+}
+
+// SelectGroup selects up to count distinct servers, best first, for the selectable group of
+// the proxy formats. With -distinct city the servers are also in distinct cities. Fewer servers
+// are returned when not enough are available.
+func (s *ServerSelector) SelectGroup(servers []api.LogicalServer, count int) ([]*api.LogicalServer, error) {
+	ranked, err := s.rankServers(servers)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]*api.LogicalServer, 0, count)
+	seen := make(map[string]bool)
+	for i := range ranked {
+		key := s.affinityKey(&ranked[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		selected = append(selected, &ranked[i])
+		if len(selected) == count {
+			break
+		}
+	}
+	return selected, nil
+}
+
+// affinityKey returns the key that must be unique across devices for the configured anti-affinity mode
+func (s *ServerSelector) affinityKey(server *api.LogicalServer) string {
+	if s.config.AntiAffinity == constants.AntiAffinityCity {
+		return server.ExitCountry + "/" + server.City
+	}
+	return server.Name
+}
+
+// rankServers filters servers and sorts them from best to worst
+func (s *ServerSelector) rankServers(servers []api.LogicalServer) ([]api.LogicalServer, error) {
 	filtered := s.filterServers(servers)
 
 	if s.config.Debug {
@@ -34,7 +142,7 @@ func (s *ServerSelector) SelectBest(servers []api.LogicalServer) (*api.LogicalSe
 	}
 
 	// Sort servers: first by score (descending), then by load (ascending)
-	sort.Slice(filtered, func(i, j int) bool {
+	sort.SliceStable(filtered, func(i, j int) bool {
 		// If scores are different, higher score wins
 		if filtered[i].Score != filtered[j].Score {
 			return filtered[i].Score > filtered[j].Score
@@ -43,7 +151,7 @@ func (s *ServerSelector) SelectBest(servers []api.LogicalServer) (*api.LogicalSe
 		return filtered[i].Load < filtered[j].Load
 	})
 
-	return &filtered[0], nil
+	return filtered, nil
 }
 
 func (s *ServerSelector) filterServers(servers []api.LogicalServer) []api.LogicalServer {
@@ -65,16 +173,8 @@ func (s *ServerSelector) isServerEligible(server *api.LogicalServer) bool {
 	}
 
 	// Filter by tier based on -free-only flag
-	if s.config.FreeOnly {
-		// When free-only is enabled, only accept Free tier servers
-		if server.Tier != api.TierFree {
-			return false
-		}
-	} else {
-		// Otherwise, filter out free tier servers
-		if server.Tier == api.TierFree {
-			return false
-		}
+	if !s.isTierAllowed(server) {
+		return false
 	}
 
 	// Filter by P2P support if requested (but not when using Secure Core or Free tier)
@@ -82,6 +182,11 @@ func (s *ServerSelector) isServerEligible(server *api.LogicalServer) bool {
 		return false
 	}
 
+	// Filter by Streaming support if requested
+	if s.config.StreamingOnly && server.Features&api.FeatureStreaming == 0 {
+		return false
+	}
+
 	// Filter by Secure Core if requested
 	if s.config.SecureCoreOnly && server.Features&api.FeatureSecureCore == 0 {
 		return false
@@ -100,6 +205,20 @@ func (s *ServerSelector) isServerEligible(server *api.LogicalServer) bool {
 	return true
 }
 
+// isTierAllowed checks the server tier against the account plan and the -free-only flag
+func (s *ServerSelector) isTierAllowed(server *api.LogicalServer) bool {
+	if s.account != nil && server.Tier > s.account.MaxTier {
+		return false
+	}
+
+	if s.config.FreeOnly {
+		// When free-only is enabled, only accept Free tier servers
+		return server.Tier == api.TierFree
+	}
+	// Otherwise, filter out free tier servers
+	return server.Tier != api.TierFree
+}
+
 func (s *ServerSelector) isCountryMatch(server *api.LogicalServer) bool {
 	for _, country := range s.config.Countries {
 		if server.ExitCountry == country {
@@ -112,15 +231,182 @@ func (s *ServerSelector) isCountryMatch(server *api.LogicalServer) bool {
 func (s *ServerSelector) buildNoServersError() error {
 	errMsg := fmt.Sprintf("No suitable servers found for countries: %v", s.config.Countries)
 
+	var filters []string
 	if s.config.SecureCoreOnly {
-		errMsg += " with Secure Core"
+		filters = append(filters, "Secure Core")
 	} else if s.config.P2PServersOnly {
-		errMsg += " with P2P support"
+		filters = append(filters, "P2P support")
+	}
+	if s.config.StreamingOnly {
+		filters = append(filters, "Streaming support")
+	}
+	if len(filters) > 0 {
+		errMsg += " with " + strings.Join(filters, " and ")
 	}
 
 	return errors.New(errMsg)
 }
 
+// SelectPinned returns the server requested with -server and/or -physical-id.
+// Ranking is bypassed, but the server must exist, be online and match the allowed tier.
+func (s *ServerSelector) SelectPinned(servers []api.LogicalServer) (*api.LogicalServer, *api.PhysicalServer, error) {
+	server, physicalServer, err := s.findPinned(servers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if server.Status != constants.StatusOnline {
+		return nil, nil, fmt.Errorf("server %s is offline", server.Name)
+	}
+
+	if !s.isTierAllowed(server) {
+		return nil, nil, s.buildTierError(server)
+	}
+
+	if physicalServer == nil {
+		physicalServer = GetBestPhysicalServer(server)
+		if physicalServer == nil {
+			return nil, nil, fmt.Errorf("server %s has no physical servers", server.Name)
+		}
+	}
+
+	if physicalServer.Status != constants.StatusOnline {
+		return nil, nil, fmt.Errorf("physical server %s of %s is offline", physicalServer.ID, server.Name)
+	}
+
+	return server, physicalServer, nil
+}
+
+// findPinned locates the pinned logical server and, if -physical-id is set, the pinned physical server
+func (s *ServerSelector) findPinned(servers []api.LogicalServer) (*api.LogicalServer, *api.PhysicalServer, error) {
+	for i := range servers {
+		server := &servers[i]
+
+		if s.config.ServerName != "" && !strings.EqualFold(server.Name, s.config.ServerName) {
+			continue
+		}
+
+		if s.config.PhysicalServerID == "" {
+			return server, nil, nil
+		}
+
+		for j := range server.Servers {
+			if server.Servers[j].ID == s.config.PhysicalServerID {
+				return server, &server.Servers[j], nil
+			}
+		}
+
+		if s.config.ServerName != "" {
+			return nil, nil, fmt.Errorf("physical server %s does not belong to server %s", s.config.PhysicalServerID, server.Name)
+		}
+	}
+
+	if s.config.ServerName != "" {
+		return nil, nil, fmt.Errorf("server %s not found", s.config.ServerName)
+	}
+	return nil, nil, fmt.Errorf("physical server %s not found", s.config.PhysicalServerID)
+}
+
+// SelectStored returns the physical server a device was previously configured with, provided
+// it is still online and eligible under the current selection criteria
+func (s *ServerSelector) SelectStored(servers []api.LogicalServer, physicalID string) (*api.LogicalServer, *api.PhysicalServer, bool) {
+	if physicalID == "" {
+		return nil, nil, false
+	}
+
+	for i := range servers {
+		server := &servers[i]
+		for j := range server.Servers {
+			if server.Servers[j].ID != physicalID {
+				continue
+			}
+			if !s.isServerEligible(server) || server.Servers[j].Status != constants.StatusOnline {
+				return nil, nil, false
+			}
+			return server, &server.Servers[j], true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// SelectRenewal selects a server for each device in -renew mode. A device keeps its stored
+// physical server (stored[i], empty if none) while SelectStored accepts it and, with -distinct,
+// no earlier device kept a server with the same key; the other devices get the best remaining
+// servers of the ranked list, as with SelectN.
+func (s *ServerSelector) SelectRenewal(servers []api.LogicalServer, stored []string) ([]*api.LogicalServer, []*api.PhysicalServer, error) {
+	distinct := s.config.AntiAffinity != "" && s.config.AntiAffinity != constants.AntiAffinityNone
+	selected := make([]*api.LogicalServer, len(stored))
+	physical := make([]*api.PhysicalServer, len(stored))
+	seen := make(map[string]bool)
+	missing := 0
+
+	for i, id := range stored {
+		server, physicalServer, ok := s.SelectStored(servers, id)
+		if ok && distinct {
+			key := s.affinityKey(server)
+			ok = !seen[key]
+			seen[key] = true
+		}
+		if !ok {
+			missing++
+			continue
+		}
+		selected[i], physical[i] = server, physicalServer
+	}
+	if missing == 0 {
+		return selected, physical, nil
+	}
+
+	ranked, err := s.rankServers(servers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Candidates without an online physical server are skipped, as SelectStored does
+	usable := func(server *api.LogicalServer) bool {
+		physicalServer := GetBestPhysicalServer(server)
+		return physicalServer != nil && physicalServer.Status == constants.StatusOnline &&
+			(!distinct || !seen[s.affinityKey(server)])
+	}
+
+	next := 0
+	for i := range selected {
+		if selected[i] != nil {
+			continue
+		}
+		for next < len(ranked) && !usable(&ranked[next]) {
+			next++
+		}
+		if next == len(ranked) {
+			if distinct {
+				return nil, nil, fmt.Errorf("only %d servers with distinct %s available for %d devices",
+					len(seen), s.config.AntiAffinity, len(stored))
+			}
+			return nil, nil, fmt.Errorf("no server with an online physical server available")
+		}
+		if distinct {
+			seen[s.affinityKey(&ranked[next])] = true
+		}
+		selected[i] = &ranked[next]
+		physical[i] = GetBestPhysicalServer(selected[i])
+	}
+
+	return selected, physical, nil
+}
+
+func (s *ServerSelector) buildTierError(server *api.LogicalServer) error {
+	if s.account != nil && server.Tier > s.account.MaxTier {
+		return fmt.Errorf("server %s is a %s tier server, but your account (%s) only has access to %s tier servers",
+			server.Name, api.GetTierName(server.Tier), s.account.GetPlanName(), api.GetTierName(s.account.MaxTier))
+	}
+	if s.config.FreeOnly {
+		return fmt.Errorf("server %s is a %s tier server, but -free-only only allows Free tier servers",
+			server.Name, api.GetTierName(server.Tier))
+	}
+	return fmt.Errorf("server %s is a Free tier server; use -free-only to select Free tier servers", server.Name)
+}
+
 // GetBestPhysicalServer returns the best physical server from a logical server
 func GetBestPhysicalServer(server *api.LogicalServer) *api.PhysicalServer {
 	if len(server.Servers) == 0 {
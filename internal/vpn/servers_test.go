@@ -0,0 +1,281 @@
+package vpn
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/config"
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+func testServers() []api.LogicalServer {
+	return []api.LogicalServer{
+		{
+			Name: "US-CA#42", ExitCountry: "US", City: "Los Angeles", Tier: api.TierPlus,
+			Features: api.FeatureP2P, Status: constants.StatusOnline, Score: 1.5,
+			Servers: []api.PhysicalServer{
+				{ID: "phys-a", EntryIP: "10.0.0.1", Status: 0},
+				{ID: "phys-b", EntryIP: "10.0.0.2", Status: constants.StatusOnline},
+			},
+		},
+		{
+			Name: "US-NY#7", ExitCountry: "US", City: "New York", Tier: api.TierPlus,
+			Status: 0, Score: 2.0,
+			Servers: []api.PhysicalServer{{ID: "phys-c", Status: constants.StatusOnline}},
+		},
+		{
+			Name: "US-FREE#1", ExitCountry: "US", City: "Miami", Tier: api.TierFree,
+			Status: constants.StatusOnline, Score: 3.0,
+			Servers: []api.PhysicalServer{{ID: "phys-d", Status: constants.StatusOnline}},
+		},
+	}
+}
+
+func TestSelectPinnedByName(t *testing.T) {
+	selector := NewServerSelector(&config.Config{ServerName: "us-ca#42"})
+
+	server, physicalServer, err := selector.SelectPinned(testServers())
+	if err != nil {
+		t.Fatalf("SelectPinned failed: %v", err)
+	}
+
+	if server.Name != "US-CA#42" {
+		t.Errorf("Expected US-CA#42, got %s", server.Name)
+	}
+
+	// The first online physical server should be used
+	if physicalServer.ID != "phys-b" {
+		t.Errorf("Expected physical server phys-b, got %s", physicalServer.ID)
+	}
+}
+
+func TestSelectPinnedByPhysicalID(t *testing.T) {
+	selector := NewServerSelector(&config.Config{PhysicalServerID: "phys-b"})
+
+	server, physicalServer, err := selector.SelectPinned(testServers())
+	if err != nil {
+		t.Fatalf("SelectPinned failed: %v", err)
+	}
+
+	if server.Name != "US-CA#42" || physicalServer.ID != "phys-b" {
+		t.Errorf("Expected US-CA#42/phys-b, got %s/%s", server.Name, physicalServer.ID)
+	}
+}
+
+func TestSelectPinnedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{"unknown server", config.Config{ServerName: "CH#1"}, "server CH#1 not found"},
+		{"unknown physical", config.Config{PhysicalServerID: "nope"}, "physical server nope not found"},
+		{"offline server", config.Config{ServerName: "US-NY#7"}, "server US-NY#7 is offline"},
+		{"offline physical", config.Config{PhysicalServerID: "phys-a"}, "physical server phys-a of US-CA#42 is offline"},
+		{"wrong owner", config.Config{ServerName: "US-CA#42", PhysicalServerID: "phys-d"}, "does not belong to server US-CA#42"},
+		{"free without free-only", config.Config{ServerName: "US-FREE#1"}, "is a Free tier server"},
+		{"paid with free-only", config.Config{ServerName: "US-CA#42", FreeOnly: true}, "only allows Free tier servers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			_, _, err := NewServerSelector(&cfg).SelectPinned(testServers())
+			if err == nil {
+				t.Fatalf("Expected error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func rankedTestServers() []api.LogicalServer {
+	online := []api.PhysicalServer{{ID: "p", Status: constants.StatusOnline}}
+	return []api.LogicalServer{
+		{Name: "NL#1", ExitCountry: "NL", City: "Amsterdam", Tier: api.TierPlus, Status: constants.StatusOnline, Score: 9, Servers: online},
+		{Name: "NL#2", ExitCountry: "NL", City: "Amsterdam", Tier: api.TierPlus, Status: constants.StatusOnline, Score: 8, Servers: online},
+		{Name: "NL#3", ExitCountry: "NL", City: "Rotterdam", Tier: api.TierPlus, Status: constants.StatusOnline, Score: 7, Servers: online},
+	}
+}
+
+func TestSelectNAntiAffinity(t *testing.T) {
+	tests := []struct {
+		affinity string
+		count    int
+		want     []string
+	}{
+		{constants.AntiAffinityNone, 2, []string{"NL#1", "NL#1"}},
+		{constants.AntiAffinityServer, 3, []string{"NL#1", "NL#2", "NL#3"}},
+		{constants.AntiAffinityCity, 2, []string{"NL#1", "NL#3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.affinity, func(t *testing.T) {
+			selector := NewServerSelector(&config.Config{Countries: []string{"NL"}, AntiAffinity: tt.affinity})
+
+			selected, err := selector.SelectN(rankedTestServers(), tt.count)
+			if err != nil {
+				t.Fatalf("SelectN failed: %v", err)
+			}
+
+			for i, server := range selected {
+				if server.Name != tt.want[i] {
+					t.Errorf("Device %d: expected %s, got %s", i, tt.want[i], server.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectNNotEnoughDistinct(t *testing.T) {
+	selector := NewServerSelector(&config.Config{Countries: []string{"NL"}, AntiAffinity: constants.AntiAffinityCity})
+
+	_, err := selector.SelectN(rankedTestServers(), 3)
+	if err == nil || !strings.Contains(err.Error(), "only 2 servers with distinct city available for 3 devices") {
+		t.Errorf("Expected distinct city error, got %v", err)
+	}
+}
+
+func TestSelectGroup(t *testing.T) {
+	selector := NewServerSelector(&config.Config{Countries: []string{"NL"}})
+
+	selected, err := selector.SelectGroup(rankedTestServers(), 5)
+	if err != nil {
+		t.Fatalf("SelectGroup failed: %v", err)
+	}
+	var names []string
+	for _, server := range selected {
+		names = append(names, server.Name)
+	}
+	if strings.Join(names, ",") != "NL#1,NL#2,NL#3" {
+		t.Errorf("Expected the 3 distinct servers best first, got %v", names)
+	}
+}
+
+func TestAccountTierRestriction(t *testing.T) {
+	servers := append(testServers(), api.LogicalServer{
+		Name: "US-PM#1", ExitCountry: "US", Tier: api.TierPM, Status: constants.StatusOnline, Score: 10,
+		Servers: []api.PhysicalServer{{ID: "phys-e", Status: constants.StatusOnline}},
+	})
+
+	selector := NewServerSelector(&config.Config{Countries: []string{"US"}})
+	selector.SetAccount(&api.VPNAccount{PlanTitle: "VPN Plus", MaxTier: api.TierPlus})
+
+	server, err := selector.SelectBest(servers)
+	if err != nil {
+		t.Fatalf("SelectBest failed: %v", err)
+	}
+	if server.Name != "US-CA#42" {
+		t.Errorf("Expected the best Plus tier server US-CA#42, got %s", server.Name)
+	}
+
+	pinned := NewServerSelector(&config.Config{ServerName: "US-PM#1"})
+	pinned.SetAccount(&api.VPNAccount{PlanTitle: "VPN Plus", MaxTier: api.TierPlus})
+	_, _, err = pinned.SelectPinned(servers)
+	if err == nil || !strings.Contains(err.Error(), "your account (VPN Plus) only has access to Plus tier servers") {
+		t.Errorf("Expected account tier error, got %v", err)
+	}
+}
+
+func TestApplyAccountTier(t *testing.T) {
+	free := &api.VPNAccount{MaxTier: api.TierFree}
+
+	cfg := &config.Config{}
+	if err := ApplyAccountTier(cfg, free); err != nil {
+		t.Fatalf("ApplyAccountTier failed: %v", err)
+	}
+	if !cfg.FreeOnly {
+		t.Error("Expected Free account to switch to Free tier servers")
+	}
+
+	if err := ApplyAccountTier(&config.Config{SecureCoreOnly: true}, free); err == nil {
+		t.Error("Expected Secure Core to be refused for a Free account")
+	}
+
+	paid := &config.Config{SecureCoreOnly: true}
+	if err := ApplyAccountTier(paid, &api.VPNAccount{MaxTier: api.TierPlus}); err != nil || paid.FreeOnly {
+		t.Errorf("Expected paid account to be left unchanged, got err=%v freeOnly=%v", err, paid.FreeOnly)
+	}
+}
+
+func TestSelectStored(t *testing.T) {
+	selector := NewServerSelector(&config.Config{Countries: []string{"US"}})
+
+	server, physicalServer, ok := selector.SelectStored(testServers(), "phys-b")
+	if !ok || server.Name != "US-CA#42" || physicalServer.ID != "phys-b" {
+		t.Errorf("Expected stored server US-CA#42/phys-b to be kept, got ok=%v", ok)
+	}
+
+	// Offline physical servers, offline logical servers and servers outside the filters are dropped
+	for _, id := range []string{"phys-a", "phys-c", "phys-d", "unknown", ""} {
+		if _, _, ok := selector.SelectStored(testServers(), id); ok {
+			t.Errorf("Expected stored server %q not to be kept", id)
+		}
+	}
+}
+
+func TestSelectRenewal(t *testing.T) {
+	servers := rankedTestServers()
+	for i := range servers {
+		servers[i].Servers = []api.PhysicalServer{{ID: "phys-" + strconv.Itoa(i+1), Status: constants.StatusOnline}}
+	}
+
+	tests := []struct {
+		affinity string
+		stored   []string
+		want     []string
+	}{
+		{constants.AntiAffinityNone, []string{"phys-3", "phys-3"}, []string{"NL#3", "NL#3"}},
+		{constants.AntiAffinityNone, []string{"", "phys-2"}, []string{"NL#1", "NL#2"}},
+		{constants.AntiAffinityServer, []string{"phys-3", "phys-3"}, []string{"NL#3", "NL#1"}},
+		{constants.AntiAffinityCity, []string{"phys-2", "phys-1"}, []string{"NL#2", "NL#3"}},
+	}
+
+	for _, tt := range tests {
+		selector := NewServerSelector(&config.Config{Countries: []string{"NL"}, AntiAffinity: tt.affinity})
+
+		selected, physicalServers, err := selector.SelectRenewal(servers, tt.stored)
+		if err != nil {
+			t.Fatalf("%s %v: SelectRenewal failed: %v", tt.affinity, tt.stored, err)
+		}
+		for i, server := range selected {
+			if server.Name != tt.want[i] || physicalServers[i] == nil {
+				t.Errorf("%s %v: device %d expected %s, got %s", tt.affinity, tt.stored, i, tt.want[i], server.Name)
+			}
+		}
+	}
+
+	selector := NewServerSelector(&config.Config{Countries: []string{"NL"}, AntiAffinity: constants.AntiAffinityCity})
+	if _, _, err := selector.SelectRenewal(servers, []string{"phys-1", "phys-2", "phys-3"}); err == nil {
+		t.Error("Expected an error when the cities run out")
+	}
+	// A device whose stored server is gone skips ranked servers without an online physical server
+	servers[0].Servers[0].Status = 0
+	selector = NewServerSelector(&config.Config{Countries: []string{"NL"}})
+	selected, physicalServers, err := selector.SelectRenewal(servers, []string{"gone"})
+	if err != nil || selected[0].Name != "NL#2" || physicalServers[0].ID != "phys-2" {
+		t.Errorf("Expected NL#2/phys-2 instead of the offline NL#1, got %v, %v", selected, err)
+	}
+}
+
+func TestBuildNoServersError(t *testing.T) {
+	tests := []struct {
+		cfg  config.Config
+		want string
+	}{
+		{config.Config{Countries: []string{"NL"}}, "No suitable servers found for countries: [NL]"},
+		{config.Config{Countries: []string{"NL"}, P2PServersOnly: true}, "No suitable servers found for countries: [NL] with P2P support"},
+		{config.Config{Countries: []string{"NL"}, StreamingOnly: true}, "No suitable servers found for countries: [NL] with Streaming support"},
+		{config.Config{Countries: []string{"NL"}, SecureCoreOnly: true, StreamingOnly: true}, "No suitable servers found for countries: [NL] with Secure Core and Streaming support"},
+	}
+
+	for _, tt := range tests {
+		if err := NewServerSelector(&tt.cfg).buildNoServersError(); err.Error() != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, err.Error())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package vpn
+
+import (
+	"fmt"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/config"
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// GetSessions fetches the account's active VPN connections
+func (c *Client) GetSessions() ([]api.VPNSession, error) {
+	var response api.VPNSessionsResponse
+	if err := c.getJSON(constants.SessionsPath, &response); err != nil {
+		return nil, err
+	}
+
+	if !constants.IsSuccessCode(response.Code) {
+		return nil, fmt.Errorf("API returned error code: %d", response.Code)
+	}
+
+	return response.Sessions, nil
+}
+
+// ConnectionUsage summarizes the account's connection usage for a run
+type ConnectionUsage struct {
+	Active     int // Connections currently active on the account
+	Max        int // Plan limit (0 = unknown)
+	NewDevices int // Devices this run is about to register
+}
+
+// Exceeded reports whether using all new devices would go over the plan's limit
+func (u ConnectionUsage) Exceeded() bool {
+	return u.Max > 0 && u.Active+u.NewDevices > u.Max
+}
+
+// String returns a one-line usage summary
+func (u ConnectionUsage) String() string {
+	limit := "unknown"
+	if u.Max > 0 {
+		limit = fmt.Sprintf("%d", u.Max)
+	}
+	return fmt.Sprintf("%d active of %s allowed, %d new device(s) in this run", u.Active, limit, u.NewDevices)
+}
+
+// CheckConnectionLimit applies the -connection-limit policy: it prints a warning in
+// warn mode and returns an error in refuse mode when the limit would be exceeded
+func CheckConnectionLimit(cfg *config.Config, usage ConnectionUsage) error {
+	if cfg.ConnectionLimit == constants.ConnectionLimitIgnore || !usage.Exceeded() {
+		return nil
+	}
+
+	msg := fmt.Sprintf("connection limit would be exceeded (%s)", usage)
+	if cfg.ConnectionLimit == constants.ConnectionLimitRefuse {
+		return fmt.Errorf("%s; use -connection-limit=warn to generate anyway", msg)
+	}
+
+	fmt.Printf("Warning: %s; connections beyond %d will be refused\n", msg, usage.Max)
+	return nil
+}
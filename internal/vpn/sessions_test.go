@@ -0,0 +1,33 @@
+package vpn
+
+import (
+	"testing"
+
+	"protonvpn-wg-confgen/internal/config"
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+func TestCheckConnectionLimit(t *testing.T) {
+	over := ConnectionUsage{Active: 9, Max: 10, NewDevices: 2}
+	within := ConnectionUsage{Active: 8, Max: 10, NewDevices: 2}
+	unknown := ConnectionUsage{Active: 50, NewDevices: 2}
+
+	tests := []struct {
+		mode    string
+		usage   ConnectionUsage
+		wantErr bool
+	}{
+		{constants.ConnectionLimitRefuse, over, true},
+		{constants.ConnectionLimitRefuse, within, false},
+		{constants.ConnectionLimitRefuse, unknown, false},
+		{constants.ConnectionLimitWarn, over, false},
+		{constants.ConnectionLimitIgnore, over, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckConnectionLimit(&config.Config{ConnectionLimit: tt.mode}, tt.usage)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("mode=%s usage=%s: expected error=%v, got %v", tt.mode, tt.usage, tt.wantErr, err)
+		}
+	}
+}
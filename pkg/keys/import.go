@@ -0,0 +1,76 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrX25519Key is returned when an imported key is a plain X25519 (WireGuard) key.
+// ProtonVPN registers the Ed25519 key the X25519 key is derived from, and the
+// derivation cannot be reversed.
+var ErrX25519Key = errors.New("the key is a 32-byte X25519 WireGuard key, which cannot be registered with ProtonVPN; " +
+	"supply the Ed25519 private key it was derived from (PKCS #8 PEM, e.g. from `openssl genpkey -algorithm ed25519`)")
+
+// Parse imports an Ed25519 private key given as PKCS #8 PEM or as the base64 64-byte private key.
+// A base64 32-byte key is rejected with ErrX25519Key, since that is what `wg genkey` produces.
+func Parse(data []byte) (*KeyPair, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("the private key is empty")
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		return parsePEM(block)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("the private key is neither PEM nor base64")
+	}
+
+	switch len(raw) {
+	case ed25519.PrivateKeySize:
+		keyPair, err := FromSeed(raw[:ed25519.SeedSize])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(keyPair.PublicKey(), raw[ed25519.SeedSize:]) {
+			return nil, fmt.Errorf("the Ed25519 private key is inconsistent: its public half does not match the seed")
+		}
+		return keyPair, nil
+	case 32:
+		return nil, ErrX25519Key
+	default:
+		return nil, fmt.Errorf("unsupported private key length: %d bytes (expected a 64-byte Ed25519 key)", len(raw))
+	}
+}
+
+func parsePEM(block *pem.Block) (*KeyPair, error) {
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q (expected an unencrypted PKCS #8 \"PRIVATE KEY\")", block.Type)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS #8 private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return FromSeed(key.Seed())
+	case *ecdh.PrivateKey:
+		if key.Curve() == ecdh.X25519() {
+			return nil, ErrX25519Key
+		}
+		return nil, fmt.Errorf("unsupported private key type: ECDH (expected Ed25519)")
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T (expected Ed25519)", key)
+	}
+}
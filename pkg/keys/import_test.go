@@ -0,0 +1,66 @@
+package keys
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	keyPair, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	pkcs8, err := keyPair.PrivateKeyPKCS8Pem()
+	if err != nil {
+		t.Fatalf("PrivateKeyPKCS8Pem failed: %v", err)
+	}
+	raw := base64.StdEncoding.EncodeToString(keyPair.PrivateKey())
+
+	for name, data := range map[string]string{"pkcs8": pkcs8, "base64": raw + "\n"} {
+		imported, err := Parse([]byte(data))
+		if err != nil {
+			t.Errorf("%s: Parse failed: %v", name, err)
+			continue
+		}
+		if imported.ToX25519Base64() != keyPair.ToX25519Base64() {
+			t.Errorf("%s: imported key does not match", name)
+		}
+	}
+}
+
+func TestParseRejectsX25519(t *testing.T) {
+	keyPair, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(x25519)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+	}
+
+	for name, data := range map[string]string{
+		"wg genkey": keyPair.ToX25519Base64(),
+		"pkcs8":     string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrX25519Key) {
+			t.Errorf("%s: expected ErrX25519Key, got %v", name, err)
+		}
+	}
+
+	for _, data := range []string{"", "not a key", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected error for %q", data)
+		}
+	}
+}
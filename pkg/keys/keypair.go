@@ -0,0 +1,96 @@
+// Package keys handles the Ed25519 key pairs registered with ProtonVPN certificates.
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+
+	vpned25519 "github.com/ProtonVPN/go-vpn-lib/ed25519"
+)
+
+// KeyPair is an Ed25519 key pair that can be persisted and restored.
+// Its encodings match github.com/ProtonVPN/go-vpn-lib/ed25519, which cannot be rebuilt from a stored key.
+type KeyPair struct {
+	private ed25519.PrivateKey
+}
+
+// Generate creates a new random key pair
+func Generate() (*KeyPair, error) {
+	libKeyPair, err := vpned25519.NewKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	defer libKeyPair.Clear()
+
+	return FromSeed(libKeyPair.PrivateKeyBytes())
+}
+
+// FromSeed restores a key pair from its 32-byte Ed25519 seed
+func FromSeed(seed []byte) (*KeyPair, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid Ed25519 seed length: %d (expected %d)", len(seed), ed25519.SeedSize)
+	}
+	return &KeyPair{private: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// Seed returns the 32-byte Ed25519 seed
+func (k *KeyPair) Seed() []byte {
+	return k.private.Seed()
+}
+
+// PublicKey returns the Ed25519 public key
+func (k *KeyPair) PublicKey() ed25519.PublicKey {
+	return k.private.Public().(ed25519.PublicKey)
+}
+
+// PrivateKey returns the Ed25519 private key
+func (k *KeyPair) PrivateKey() ed25519.PrivateKey {
+	return k.private
+}
+
+// PublicKeyPKIXPem returns the public key in PKIX, ASN.1 DER form as PEM
+func (k *KeyPair) PublicKeyPKIXPem() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(k.PublicKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// PrivateKeyPKCS8Pem returns the private key in PKCS #8, ASN.1 DER form as PEM
+func (k *KeyPair) PrivateKeyPKCS8Pem() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// Fingerprint returns the base64 SHA-512 digest of the raw Ed25519 public key
+func (k *KeyPair) Fingerprint() string {
+	return PublicKeyFingerprint(k.PublicKey())
+}
+
+// PublicKeyFingerprint returns the base64 SHA-512 digest of a raw Ed25519 public key
+func PublicKeyFingerprint(publicKey ed25519.PublicKey) string {
+	hash := sha512.Sum512(publicKey)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// ToX25519 converts the key to the X25519 secret key used by WireGuard
+func (k *KeyPair) ToX25519() []byte {
+	hash := sha512.Sum512(k.Seed())
+	hash[0] &= 0xF8
+	hash[31] &= 0x7F
+	hash[31] |= 0x40
+	return hash[:32]
+}
+
+// ToX25519Base64 converts the key to the base64 X25519 secret key used in WireGuard configs
+func (k *KeyPair) ToX25519Base64() string {
+	return base64.StdEncoding.EncodeToString(k.ToX25519())
+}
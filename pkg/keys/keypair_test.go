@@ -0,0 +1,41 @@
+package keys
+
+import (
+	"testing"
+
+	vpned25519 "github.com/ProtonVPN/go-vpn-lib/ed25519"
+)
+
+func TestKeyPairMatchesVPNLib(t *testing.T) {
+	libKeyPair, err := vpned25519.NewKeyPair()
+	if err != nil {
+		t.Fatalf("NewKeyPair failed: %v", err)
+	}
+
+	keyPair, err := FromSeed(libKeyPair.PrivateKeyBytes())
+	if err != nil {
+		t.Fatalf("FromSeed failed: %v", err)
+	}
+
+	if got, want := keyPair.ToX25519Base64(), libKeyPair.ToX25519Base64(); got != want {
+		t.Errorf("X25519 key mismatch: got %s, want %s", got, want)
+	}
+
+	got, err := keyPair.PublicKeyPKIXPem()
+	if err != nil {
+		t.Fatalf("PublicKeyPKIXPem failed: %v", err)
+	}
+	want, err := libKeyPair.PublicKeyPKIXPem()
+	if err != nil {
+		t.Fatalf("lib PublicKeyPKIXPem failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Public key PEM mismatch:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestFromSeedInvalidLength(t *testing.T) {
+	if _, err := FromSeed(make([]byte, 16)); err == nil {
+		t.Error("Expected error for short seed")
+	}
+}
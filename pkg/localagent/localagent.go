@@ -0,0 +1,364 @@
+// Package localagent implements a client for the ProtonVPN local agent, the TLS control
+// connection to the VPN gateway that is authenticated with the client certificate.
+// It reports the connection status and changes certificate features at runtime.
+package localagent
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// DefaultAddress is where the local agent listens inside the tunnel
+const DefaultAddress = "10.2.0.1:65432"
+
+// DefaultTimeout bounds dialing and waiting for each reply
+const DefaultTimeout = 10 * time.Second
+
+// maxMessageSize guards against a corrupt length prefix
+const maxMessageSize = 1 << 20
+
+// Connection states reported in Status.State
+const (
+	StateConnected  = "connected"
+	StateSoftJailed = "jailed"
+	StateHardJailed = "hard-jailed"
+)
+
+// Features are the runtime features of the connection. Nil fields are left unchanged.
+type Features struct {
+	NetShieldLevel *int    `json:"netshield-level,omitempty"`
+	RandomizedNAT  *bool   `json:"randomized-nat,omitempty"`
+	SplitTCP       *bool   `json:"split-tcp,omitempty"`
+	PortForwarding *bool   `json:"port-forwarding,omitempty"`
+	Bouncing       *string `json:"bouncing,omitempty"`
+}
+
+// IsEmpty reports whether no feature is set
+func (f *Features) IsEmpty() bool {
+	return f.NetShieldLevel == nil && f.RandomizedNAT == nil && f.SplitTCP == nil &&
+		f.PortForwarding == nil && f.Bouncing == nil
+}
+
+// appliedIn reports whether every feature set in f has the same value in other
+func (f *Features) appliedIn(other *Features) bool {
+	return intApplied(f.NetShieldLevel, other.NetShieldLevel) &&
+		boolApplied(f.RandomizedNAT, other.RandomizedNAT) &&
+		boolApplied(f.SplitTCP, other.SplitTCP) &&
+		boolApplied(f.PortForwarding, other.PortForwarding) &&
+		(f.Bouncing == nil || other.Bouncing != nil && *f.Bouncing == *other.Bouncing)
+}
+
+func intApplied(want, got *int) bool {
+	return want == nil || got != nil && *want == *got
+}
+
+func boolApplied(want, got *bool) bool {
+	return want == nil || got != nil && *want == *got
+}
+
+// Status is the connection status reported by the agent
+type Status struct {
+	State              string                 `json:"state"`
+	Features           Features               `json:"features"`
+	Reason             *Reason                `json:"reason,omitempty"`
+	SwitchTo           string                 `json:"please-switch-to,omitempty"`
+	ConnectionDetails  *ConnectionDetails     `json:"connection-details,omitempty"`
+	FeaturesStatistics map[string]interface{} `json:"features-statistics,omitempty"`
+	Restrictions       []string               `json:"restrictions,omitempty"`
+}
+
+// Reason explains a jailed state
+type Reason struct {
+	Code        int    `json:"code"`
+	Final       bool   `json:"final"`
+	Description string `json:"description"`
+}
+
+// ConnectionDetails describes the device and server as seen by the gateway
+type ConnectionDetails struct {
+	DeviceIP      string `json:"device-ip"`
+	DeviceCountry string `json:"device-country"`
+	ServerIPv4    string `json:"server-ipv4"`
+	ServerIPv6    string `json:"server-ipv6"`
+}
+
+// Error is an error message sent by the agent
+type Error struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("local agent error %d: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("local agent error %d: %s", e.Code, DescribeCode(e.Code))
+}
+
+// Agent error and reason codes
+const (
+	CodeGuestSession           = 86100
+	CodeCertificateExpired     = 86101
+	CodeCertificateRevoked     = 86102
+	CodeKeyUsedMultipleTimes   = 86103
+	CodeRestrictedServer       = 86104
+	CodeBadCertSignature       = 86105
+	CodeCertNotProvided        = 86106
+	CodeMaxSessionsUnknown     = 86110
+	CodeMaxSessionsFree        = 86111
+	CodeMaxSessionsBasic       = 86112
+	CodeMaxSessionsPlus        = 86113
+	CodeMaxSessionsVisionary   = 86114
+	CodeMaxSessionsPro         = 86115
+	CodeServerError            = 86150
+	CodePolicyViolationLowPlan = 86151
+)
+
+// DescribeCode returns a short description of an agent error or reason code
+func DescribeCode(code int) string {
+	switch code {
+	case CodeGuestSession:
+		return "guest session"
+	case CodeCertificateExpired:
+		return "certificate expired"
+	case CodeCertificateRevoked:
+		return "certificate revoked"
+	case CodeKeyUsedMultipleTimes:
+		return "key used by multiple connections"
+	case CodeRestrictedServer:
+		return "server restricted for this account"
+	case CodeBadCertSignature:
+		return "bad certificate signature"
+	case CodeCertNotProvided:
+		return "no client certificate provided"
+	case CodeMaxSessionsUnknown, CodeMaxSessionsFree, CodeMaxSessionsBasic,
+		CodeMaxSessionsPlus, CodeMaxSessionsVisionary, CodeMaxSessionsPro:
+		return "maximum number of VPN sessions reached"
+	case CodeServerError:
+		return "server error"
+	case CodePolicyViolationLowPlan:
+		return "feature not available on this plan"
+	default:
+		return "unknown error"
+	}
+}
+
+// Config holds the TLS settings of a local agent connection
+type Config struct {
+	Certificate tls.Certificate
+	// RootCAs verifies the gateway certificate. When nil, the certificate is not
+	// verified and the gateway is trusted because it is only reachable through the tunnel.
+	RootCAs    *x509.CertPool
+	ServerName string
+	Timeout    time.Duration // DefaultTimeout if zero
+}
+
+// NewCertificate combines a PEM client certificate with the Ed25519 key it was issued for
+func NewCertificate(certPEM string, privateKey ed25519.PrivateKey) (tls.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return tls.Certificate{}, fmt.Errorf("client certificate is not a PEM certificate")
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+	publicKey, ok := leaf.PublicKey.(ed25519.PublicKey)
+	if !ok || !publicKey.Equal(privateKey.Public()) {
+		return tls.Certificate{}, fmt.Errorf("client certificate was not issued for this key")
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{block.Bytes},
+		PrivateKey:  privateKey,
+		Leaf:        leaf,
+	}, nil
+}
+
+// Client is an open local agent connection
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	status  *Status
+}
+
+// Dial connects to the agent and waits for the status it sends on connect
+func Dial(address string, cfg *Config) (*Client, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cfg.Certificate},
+		RootCAs:      cfg.RootCAs,
+		ServerName:   cfg.ServerName,
+		MinVersion:   tls.VersionTLS12,
+		// Without RootCAs the gateway is authenticated by the WireGuard tunnel instead
+		InsecureSkipVerify: cfg.RootCAs == nil, // #nosec G402
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to local agent at %s: %w", address, err)
+	}
+
+	client := &Client{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+	status, err := client.readStatus()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client.status = status
+	return client, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// InitialStatus returns the status received when the connection was established
+func (c *Client) InitialStatus() *Status {
+	return c.status
+}
+
+// Status requests the current status, optionally with feature statistics
+func (c *Client) Status(withStatistics bool) (*Status, error) {
+	request := struct {
+		FeaturesStatistics bool `json:"features-statistics"`
+	}{withStatistics}
+	if err := c.send("status-get", request); err != nil {
+		return nil, err
+	}
+
+	status, err := c.readStatus()
+	if err != nil {
+		return nil, err
+	}
+	c.status = status
+	return status, nil
+}
+
+// SetFeatures changes features of the connection and returns the status that confirms them.
+// The agent may push other status updates first; they are skipped until the features are applied.
+func (c *Client) SetFeatures(features Features) (*Status, error) {
+	if features.IsEmpty() {
+		return nil, fmt.Errorf("no features to set")
+	}
+	if err := c.send("features-set", features); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	for {
+		status, err := c.readStatusUntil(deadline)
+		if err != nil {
+			return nil, err
+		}
+		c.status = status
+		if features.appliedIn(&status.Features) {
+			return status, nil
+		}
+		if status.State == StateHardJailed && status.Reason != nil {
+			return status, fmt.Errorf("features not applied: %s", describeReason(status.Reason))
+		}
+	}
+}
+
+func describeReason(reason *Reason) string {
+	if reason.Description != "" {
+		return reason.Description
+	}
+	return DescribeCode(reason.Code)
+}
+
+// send writes one length-prefixed JSON message of the form {"key": value}
+func (c *Client) send(key string, value interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data))) // #nosec G115 -- bounded by the message size
+	copy(frame[4:], data)
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to send %s: %w", key, err)
+	}
+	return nil
+}
+
+func (c *Client) readStatus() (*Status, error) {
+	return c.readStatusUntil(time.Now().Add(c.timeout))
+}
+
+// readStatusUntil reads messages until a status arrives; an error message is returned as *Error
+func (c *Client) readStatusUntil(deadline time.Time) (*Status, error) {
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	for {
+		message, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		if raw, ok := message["error"]; ok {
+			agentErr := &Error{}
+			if err := json.Unmarshal(raw, agentErr); err != nil {
+				return nil, fmt.Errorf("failed to decode agent error: %w", err)
+			}
+			return nil, agentErr
+		}
+		if raw, ok := message["status"]; ok {
+			status := &Status{}
+			if err := json.Unmarshal(raw, status); err != nil {
+				return nil, fmt.Errorf("failed to decode agent status: %w", err)
+			}
+			return status, nil
+		}
+		// Unknown message types are ignored, as official clients do
+	}
+}
+
+// receive reads one length-prefixed JSON message
+func (c *Client) receive() (map[string]json.RawMessage, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read from local agent: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("local agent message too large: %d bytes", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, data); err != nil {
+		return nil, fmt.Errorf("failed to read from local agent: %w", err)
+	}
+
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode local agent message: %w", err)
+	}
+	return message, nil
+}
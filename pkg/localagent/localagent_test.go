@@ -0,0 +1,297 @@
+package localagent
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeAgent is a local TLS stand-in for the gateway's local agent. It requires a client
+// certificate, sends a status on connect and applies every features-set request.
+type fakeAgent struct {
+	listener net.Listener
+	rootCAs  *x509.CertPool
+	features Features
+	reject   *Error // Sent instead of a status after features-set when set
+}
+
+func newFakeAgent(t *testing.T) *fakeAgent {
+	t.Helper()
+
+	serverCert, rootCAs := newTestServerCertificate(t, "node-ch-01.protonvpn.net")
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	level := 1
+	agent := &fakeAgent{listener: listener, rootCAs: rootCAs, features: Features{NetShieldLevel: &level}}
+	go agent.serve()
+	return agent
+}
+
+func (a *fakeAgent) serve() {
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			return
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *fakeAgent) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	if err := writeMessage(conn, "status", a.status(nil)); err != nil {
+		return
+	}
+	for {
+		message, err := readMessage(reader)
+		if err != nil {
+			return
+		}
+		if raw, ok := message["features-set"]; ok {
+			if a.reject != nil {
+				_ = writeMessage(conn, "error", a.reject)
+				continue
+			}
+			var features Features
+			_ = json.Unmarshal(raw, &features)
+			if features.NetShieldLevel != nil {
+				a.features.NetShieldLevel = features.NetShieldLevel
+			}
+			if features.PortForwarding != nil {
+				a.features.PortForwarding = features.PortForwarding
+			}
+			_ = writeMessage(conn, "status", a.status(nil))
+		}
+		if raw, ok := message["status-get"]; ok {
+			var request struct {
+				FeaturesStatistics bool `json:"features-statistics"`
+			}
+			_ = json.Unmarshal(raw, &request)
+			var stats map[string]interface{}
+			if request.FeaturesStatistics {
+				stats = map[string]interface{}{"netshield-level": map[string]interface{}{"ads": 3}}
+			}
+			_ = writeMessage(conn, "status", a.status(stats))
+		}
+	}
+}
+
+func (a *fakeAgent) status(stats map[string]interface{}) *Status {
+	return &Status{
+		State:              StateConnected,
+		Features:           a.features,
+		ConnectionDetails:  &ConnectionDetails{DeviceIP: "203.0.113.7", ServerIPv4: "185.159.157.1"},
+		FeaturesStatistics: stats,
+	}
+}
+
+func writeMessage(w io.Writer, key string, value interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readMessage(r io.Reader) (map[string]json.RawMessage, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	var message map[string]json.RawMessage
+	err := json.Unmarshal(data, &message)
+	return message, err
+}
+
+// newTestServerCertificate returns a server certificate for name and a pool with its self-signed CA
+func newTestServerCertificate(t *testing.T, name string) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	_, caKey, _ := ed25519.GenerateKey(rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	_, serverKey, _ := ed25519.GenerateKey(rand.Reader)
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caCert, serverKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}, pool
+}
+
+// newTestClientCertificate returns a PEM client certificate and the key it was issued for
+func newTestClientCertificate(t *testing.T) (string, ed25519.PrivateKey) {
+	t.Helper()
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, issuerKey, _ := ed25519.GenerateKey(rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "WireGuard-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, publicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), privateKey
+}
+
+func dialFakeAgent(t *testing.T, agent *fakeAgent) *Client {
+	t.Helper()
+
+	certPEM, privateKey := newTestClientCertificate(t)
+	cert, err := NewCertificate(certPEM, privateKey)
+	if err != nil {
+		t.Fatalf("NewCertificate failed: %v", err)
+	}
+
+	client, err := Dial(agent.listener.Addr().String(), &Config{
+		Certificate: cert,
+		RootCAs:     agent.rootCAs,
+		ServerName:  "node-ch-01.protonvpn.net",
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestStatus(t *testing.T) {
+	client := dialFakeAgent(t, newFakeAgent(t))
+
+	initial := client.InitialStatus()
+	if initial.State != StateConnected || initial.Features.NetShieldLevel == nil || *initial.Features.NetShieldLevel != 1 {
+		t.Errorf("Unexpected initial status: %+v", initial)
+	}
+
+	status, err := client.Status(true)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.ConnectionDetails == nil || status.ConnectionDetails.DeviceIP != "203.0.113.7" {
+		t.Errorf("Unexpected connection details: %+v", status.ConnectionDetails)
+	}
+	if _, ok := status.FeaturesStatistics["netshield-level"]; !ok {
+		t.Errorf("Expected feature statistics, got %v", status.FeaturesStatistics)
+	}
+}
+
+func TestSetFeatures(t *testing.T) {
+	client := dialFakeAgent(t, newFakeAgent(t))
+
+	level, portForwarding := 2, true
+	status, err := client.SetFeatures(Features{NetShieldLevel: &level, PortForwarding: &portForwarding})
+	if err != nil {
+		t.Fatalf("SetFeatures failed: %v", err)
+	}
+	if *status.Features.NetShieldLevel != 2 || status.Features.PortForwarding == nil || !*status.Features.PortForwarding {
+		t.Errorf("Expected the requested features to be applied, got %+v", status.Features)
+	}
+
+	if _, err := client.SetFeatures(Features{}); err == nil {
+		t.Error("Expected an error when no features are given")
+	}
+}
+
+func TestSetFeaturesError(t *testing.T) {
+	agent := newFakeAgent(t)
+	agent.reject = &Error{Code: CodePolicyViolationLowPlan}
+	client := dialFakeAgent(t, agent)
+
+	portForwarding := true
+	_, err := client.SetFeatures(Features{PortForwarding: &portForwarding})
+
+	var agentErr *Error
+	if !errors.As(err, &agentErr) || agentErr.Code != CodePolicyViolationLowPlan {
+		t.Fatalf("Expected agent error %d, got %v", CodePolicyViolationLowPlan, err)
+	}
+	if agentErr.Error() != "local agent error 86151: feature not available on this plan" {
+		t.Errorf("Unexpected error message: %s", agentErr.Error())
+	}
+}
+
+func TestDialVerifiesServerName(t *testing.T) {
+	agent := newFakeAgent(t)
+	certPEM, privateKey := newTestClientCertificate(t)
+	cert, _ := NewCertificate(certPEM, privateKey)
+
+	_, err := Dial(agent.listener.Addr().String(), &Config{
+		Certificate: cert,
+		RootCAs:     agent.rootCAs,
+		ServerName:  "node-de-01.protonvpn.net",
+		Timeout:     5 * time.Second,
+	})
+	if err == nil {
+		t.Error("Expected a server name mismatch to fail")
+	}
+}
+
+func TestNewCertificateRejectsOtherKey(t *testing.T) {
+	certPEM, _ := newTestClientCertificate(t)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	if _, err := NewCertificate(certPEM, otherKey); err == nil {
+		t.Error("Expected a certificate for another key to be rejected")
+	}
+	if _, err := NewCertificate("not a certificate", otherKey); err == nil {
+		t.Error("Expected invalid PEM to be rejected")
+	}
+}
@@ -0,0 +1,213 @@
+// Package natpmp implements a NAT Port Mapping Protocol (RFC 6886) client.
+package natpmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// DefaultPort is the UDP port NAT-PMP servers listen on
+const DefaultPort = 5351
+
+// Protocol is the transport protocol of a port mapping
+type Protocol uint8
+
+// Mapping protocols, encoded as their request opcodes
+const (
+	UDP Protocol = 1
+	TCP Protocol = 2
+)
+
+// String returns the protocol name
+func (p Protocol) String() string {
+	switch p {
+	case UDP:
+		return "udp"
+	case TCP:
+		return "tcp"
+	default:
+		return fmt.Sprintf("protocol(%d)", uint8(p))
+	}
+}
+
+const (
+	version             = 0
+	opExternalAddress   = 0
+	opResponseFlag      = 128
+	externalAddressSize = 12
+	mappingSize         = 16
+)
+
+// Result codes from RFC 6886 section 3.5
+const (
+	ResultSuccess              = 0
+	ResultUnsupportedVersion   = 1
+	ResultNotAuthorized        = 2
+	ResultNetworkFailure       = 3
+	ResultOutOfResources       = 4
+	ResultUnsupportedOperation = 5
+)
+
+// ResultError is a non-zero result code returned by the gateway
+type ResultError struct {
+	Code uint16
+}
+
+func (e *ResultError) Error() string {
+	switch e.Code {
+	case ResultUnsupportedVersion:
+		return "NAT-PMP error: unsupported version"
+	case ResultNotAuthorized:
+		return "NAT-PMP error: not authorized or refused"
+	case ResultNetworkFailure:
+		return "NAT-PMP error: network failure"
+	case ResultOutOfResources:
+		return "NAT-PMP error: out of resources"
+	case ResultUnsupportedOperation:
+		return "NAT-PMP error: unsupported opcode"
+	default:
+		return fmt.Sprintf("NAT-PMP error: result code %d", e.Code)
+	}
+}
+
+// ErrNoResponse is returned when the gateway did not answer any retransmission
+var ErrNoResponse = errors.New("no response from NAT-PMP gateway")
+
+// Mapping is a port mapping granted by the gateway
+type Mapping struct {
+	Protocol     Protocol
+	InternalPort uint16
+	ExternalPort uint16
+	Lifetime     time.Duration
+	Epoch        uint32 // Seconds since the gateway's port mapping table was initialized
+}
+
+// Client sends NAT-PMP requests to a gateway
+type Client struct {
+	gateway string
+
+	// Timeout is the initial retransmission timeout; it doubles after every attempt (RFC 6886 section 3.1)
+	Timeout time.Duration
+	// Attempts is the number of times a request is sent before giving up
+	Attempts int
+}
+
+// NewClient creates a client for the gateway, given as a host or host:port (port 5351 if omitted)
+func NewClient(gateway string) *Client {
+	if _, _, err := net.SplitHostPort(gateway); err != nil {
+		gateway = net.JoinHostPort(gateway, strconv.Itoa(DefaultPort))
+	}
+
+	return &Client{
+		gateway:  gateway,
+		Timeout:  250 * time.Millisecond,
+		Attempts: 4,
+	}
+}
+
+// ExternalAddress returns the gateway's external IPv4 address
+func (c *Client) ExternalAddress() (net.IP, error) {
+	response, err := c.call([]byte{version, opExternalAddress}, externalAddressSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.IPv4(response[8], response[9], response[10], response[11]), nil
+}
+
+// AddMapping requests a mapping of internalPort, suggesting externalPort (0 lets the gateway choose).
+// The gateway may grant a different external port and lifetime than requested.
+func (c *Client) AddMapping(protocol Protocol, internalPort, externalPort uint16, lifetime time.Duration) (*Mapping, error) {
+	request := make([]byte, 12)
+	request[0] = version
+	request[1] = byte(protocol)
+	binary.BigEndian.PutUint16(request[4:6], internalPort)
+	binary.BigEndian.PutUint16(request[6:8], externalPort)
+	binary.BigEndian.PutUint32(request[8:12], uint32(lifetime/time.Second))
+
+	response, err := c.call(request, mappingSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mapping{
+		Protocol:     protocol,
+		Epoch:        binary.BigEndian.Uint32(response[4:8]),
+		InternalPort: binary.BigEndian.Uint16(response[8:10]),
+		ExternalPort: binary.BigEndian.Uint16(response[10:12]),
+		Lifetime:     time.Duration(binary.BigEndian.Uint32(response[12:16])) * time.Second,
+	}, nil
+}
+
+// DeleteMapping removes the mapping of internalPort
+func (c *Client) DeleteMapping(protocol Protocol, internalPort uint16) error {
+	_, err := c.AddMapping(protocol, internalPort, 0, 0)
+	return err
+}
+
+// call sends a request and waits for the matching response, retransmitting with exponential backoff
+func (c *Client) call(request []byte, responseSize int) ([]byte, error) {
+	conn, err := net.Dial("udp", c.gateway)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NAT-PMP gateway: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	timeout := c.Timeout
+	buf := make([]byte, 64)
+	for range c.Attempts {
+		if _, err := conn.Write(request); err != nil {
+			return nil, fmt.Errorf("failed to send NAT-PMP request: %w", err)
+		}
+
+		deadline := time.Now().Add(timeout)
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, fmt.Errorf("failed to read NAT-PMP response: %w", err)
+			}
+
+			response, err := parseResponse(buf[:n], request[1], responseSize)
+			if err != nil {
+				return nil, err
+			}
+			if response != nil {
+				return response, nil
+			}
+			// Ignore unrelated packets until the deadline
+		}
+
+		timeout *= 2
+	}
+
+	return nil, ErrNoResponse
+}
+
+// parseResponse validates a response to the request opcode.
+// It returns nil without error for packets that do not answer the request.
+func parseResponse(packet []byte, opcode byte, size int) ([]byte, error) {
+	if len(packet) < 4 || packet[0] != version || packet[1] != opcode|opResponseFlag {
+		return nil, nil
+	}
+
+	if code := binary.BigEndian.Uint16(packet[2:4]); code != ResultSuccess {
+		return nil, &ResultError{Code: code}
+	}
+
+	if len(packet) < size {
+		return nil, fmt.Errorf("short NAT-PMP response: %d bytes (expected %d)", len(packet), size)
+	}
+	return packet[:size], nil
+}
@@ -0,0 +1,127 @@
+package natpmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeGateway is a local NAT-PMP responder that grants every mapping on a fixed external port
+type fakeGateway struct {
+	conn         *net.UDPConn
+	externalPort uint16
+	result       uint16
+	requests     chan []byte
+}
+
+func newFakeGateway(t *testing.T, externalPort uint16, result uint16) *fakeGateway {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	g := &fakeGateway{conn: conn, externalPort: externalPort, result: result, requests: make(chan []byte, 16)}
+	go g.serve()
+	return g
+}
+
+func (g *fakeGateway) addr() string {
+	return g.conn.LocalAddr().String()
+}
+
+func (g *fakeGateway) serve() {
+	buf := make([]byte, 64)
+	for {
+		n, addr, err := g.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		request := append([]byte(nil), buf[:n]...)
+		g.requests <- request
+
+		var response []byte
+		switch request[1] {
+		case opExternalAddress:
+			response = make([]byte, externalAddressSize)
+			copy(response[8:], net.IPv4(203, 0, 113, 7).To4())
+		default:
+			response = make([]byte, mappingSize)
+			copy(response[8:10], request[4:6])
+			binary.BigEndian.PutUint16(response[10:12], g.externalPort)
+			copy(response[12:16], request[8:12])
+		}
+		response[1] = request[1] | opResponseFlag
+		binary.BigEndian.PutUint16(response[2:4], g.result)
+		binary.BigEndian.PutUint32(response[4:8], 1234)
+
+		_, _ = g.conn.WriteToUDP(response, addr)
+	}
+}
+
+func TestExternalAddress(t *testing.T) {
+	gateway := newFakeGateway(t, 0, ResultSuccess)
+
+	ip, err := NewClient(gateway.addr()).ExternalAddress()
+	if err != nil {
+		t.Fatalf("ExternalAddress failed: %v", err)
+	}
+	if !ip.Equal(net.IPv4(203, 0, 113, 7)) {
+		t.Errorf("Expected 203.0.113.7, got %s", ip)
+	}
+}
+
+func TestAddMapping(t *testing.T) {
+	gateway := newFakeGateway(t, 45678, ResultSuccess)
+
+	mapping, err := NewClient(gateway.addr()).AddMapping(TCP, 0, 1, 60*time.Second)
+	if err != nil {
+		t.Fatalf("AddMapping failed: %v", err)
+	}
+
+	request := <-gateway.requests
+	want := []byte{0, 2, 0, 0, 0, 0, 0, 1, 0, 0, 0, 60}
+	if string(request) != string(want) {
+		t.Errorf("Expected request %v, got %v", want, request)
+	}
+
+	if mapping.ExternalPort != 45678 || mapping.Lifetime != 60*time.Second || mapping.Epoch != 1234 || mapping.Protocol != TCP {
+		t.Errorf("Unexpected mapping: %+v", mapping)
+	}
+}
+
+func TestResultError(t *testing.T) {
+	gateway := newFakeGateway(t, 0, ResultNotAuthorized)
+
+	_, err := NewClient(gateway.addr()).AddMapping(UDP, 0, 1, 60*time.Second)
+	var resultErr *ResultError
+	if !errors.As(err, &resultErr) || resultErr.Code != ResultNotAuthorized {
+		t.Errorf("Expected not authorized error, got %v", err)
+	}
+}
+
+func TestNoResponse(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	client := NewClient(conn.LocalAddr().String())
+	client.Timeout = 10 * time.Millisecond
+	client.Attempts = 2
+
+	if _, err := client.ExternalAddress(); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("Expected ErrNoResponse, got %v", err)
+	}
+}
+
+func TestNewClientDefaultPort(t *testing.T) {
+	if got := NewClient("10.2.0.1").gateway; got != "10.2.0.1:5351" {
+		t.Errorf("Expected 10.2.0.1:5351, got %s", got)
+	}
+}
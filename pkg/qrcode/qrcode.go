@@ -0,0 +1,519 @@
+// Package qrcode encodes data as QR codes (ISO/IEC 18004, byte mode, versions 1 to 40)
+// and renders them for terminals, as PNG or as SVG.
+package qrcode
+
+import (
+	"fmt"
+)
+
+// Level is an error correction level
+type Level int
+
+// Error correction levels, recovering about 7%, 15%, 25% and 30% of the codewords
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// String returns the level's letter
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// formatBits are the error correction bits of the format information
+var formatBits = [...]int{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+// eccCodewordsPerBlock is the number of error correction codewords of each block, by level and version
+var eccCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// eccBlocks is the number of error correction blocks, by level and version
+var eccBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Version range
+const (
+	MinVersion = 1
+	MaxVersion = 40
+)
+
+// Penalty weights of the mask evaluation
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// Code is an encoded QR code
+type Code struct {
+	Version  int
+	Level    Level
+	size     int
+	modules  [][]bool // Dark modules, indexed [y][x]
+	reserved [][]bool // Function patterns, which data and masks leave alone
+}
+
+// TooLongError is returned when the data does not fit in a version 40 code
+type TooLongError struct {
+	Length   int
+	Capacity int
+	Level    Level
+}
+
+func (e *TooLongError) Error() string {
+	return fmt.Sprintf("data is %d bytes, more than the %d bytes a QR code holds at error correction level %s",
+		e.Length, e.Capacity, e.Level)
+}
+
+// Capacity returns the number of bytes a code of the version and level holds
+func Capacity(version int, level Level) int {
+	bits := dataCodewords(version, level)*8 - 4 - charCountBits(version)
+	return bits / 8
+}
+
+// Encode encodes data in byte mode in the smallest version that fits
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("invalid error correction level: %d", level)
+	}
+
+	version := MinVersion
+	for Capacity(version, level) < len(data) {
+		if version == MaxVersion {
+			return nil, &TooLongError{Length: len(data), Capacity: Capacity(MaxVersion, level), Level: level}
+		}
+		version++
+	}
+
+	code := newCode(version, level)
+	code.drawFunctionPatterns()
+	code.drawCodewords(code.addErrorCorrection(encodeData(data, version, level)))
+
+	// Keep the mask with the lowest penalty
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		code.applyMask(mask)
+		code.drawFormatBits(mask)
+		if penalty := code.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		code.applyMask(mask) // Masks are XORed, so applying one again undoes it
+	}
+	code.applyMask(best)
+	code.drawFormatBits(best)
+
+	return code, nil
+}
+
+// Size returns the number of modules on each side, without a quiet zone
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Dark reports whether the module at x, y is dark; modules outside the code are light
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.size && y < c.size && c.modules[y][x]
+}
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	code := &Code{Version: version, Level: level, size: size}
+	code.modules = make([][]bool, size)
+	code.reserved = make([][]bool, size)
+	for y := range size {
+		code.modules[y] = make([]bool, size)
+		code.reserved[y] = make([]bool, size)
+	}
+	return code
+}
+
+// rawDataModules returns the number of modules left for data and error correction
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		alignments := version/7 + 2
+		result -= (25*alignments-10)*alignments - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// dataCodewords returns the number of data codewords of the version and level
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// charCountBits returns the width of the byte mode character count
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData returns the data codewords: mode, character count, data, terminator and padding
+func encodeData(data []byte, version int, level Level) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4) // Byte mode
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := dataCodewords(version, level) * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return codewords
+}
+
+// bitBuffer is a sequence of bits, most significant first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 != 0)
+	}
+}
+
+// addErrorCorrection splits the data codewords into blocks, adds each block's error
+// correction codewords and interleaves the blocks
+func (c *Code) addErrorCorrection(data []byte) []byte {
+	blocks := eccBlocks[c.Level][c.Version]
+	eccLength := eccCodewordsPerBlock[c.Level][c.Version]
+	rawCodewords := rawDataModules(c.Version) / 8
+	shortBlocks := blocks - rawCodewords%blocks
+	shortLength := rawCodewords / blocks // Including error correction
+
+	divisor := reedSolomonDivisor(eccLength)
+	dataBlocks := make([][]byte, blocks)
+	eccs := make([][]byte, blocks)
+	offset := 0
+	for i := range blocks {
+		length := shortLength - eccLength
+		if i >= shortBlocks {
+			length++
+		}
+		dataBlocks[i] = data[offset : offset+length]
+		eccs[i] = reedSolomonRemainder(dataBlocks[i], divisor)
+		offset += length
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := 0; i <= shortLength-eccLength; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range eccLength {
+		for _, ecc := range eccs {
+			result = append(result, ecc[i])
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree, without its leading term
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// setFunction sets a function pattern module
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.reserved[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	// Timing patterns
+	for i := range c.size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their separators
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.size-4, 3)
+	c.drawFinderPattern(3, c.size-4)
+
+	// Alignment patterns, except where they would overlap the finder patterns
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format information, which is drawn with the mask
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			if x+dx >= 0 && x+dx < c.size && y+dy >= 0 && y+dy < c.size {
+				distance := max(abs(dx), abs(dy))
+				c.setFunction(x+dx, y+dy, distance != 2 && distance != 4)
+			}
+		}
+	}
+}
+
+// alignmentPositions returns the centre coordinates of the alignment patterns
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i := count - 1; i >= 1; i-- {
+		positions[i] = version*4 + 10 - (count-1-i)*step
+	}
+	return positions
+}
+
+// drawFormatBits draws both copies of the error correction level and mask
+func (c *Code) drawFormatBits(mask int) {
+	data := formatBits[c.Level]<<3 | mask
+	remainder := data
+	for range 10 {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(i))
+	}
+	c.setFunction(8, c.size-8, true) // Always dark
+}
+
+// drawVersion draws both copies of the version information of versions 7 and up
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	remainder := c.Version
+	for range 12 {
+		remainder = remainder<<1 ^ (remainder>>11)*0x1F25
+	}
+	bits := c.Version<<12 | remainder
+
+	for i := range 18 {
+		dark := bits>>i&1 != 0
+		a, b := c.size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag pattern from the bottom right corner
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern
+		}
+		for vertical := range c.size {
+			for j := range 2 {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vertical // Upward column
+				}
+				if !c.reserved[y][x] && i < len(codewords)*8 {
+					c.modules[y][x] = codewords[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by the mask pattern
+func (c *Code) applyMask(mask int) {
+	for y := range c.size {
+		for x := range c.size {
+			if c.reserved[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			c.modules[y][x] = c.modules[y][x] != invert
+		}
+	}
+}
+
+// finderLike are the module sequences penalized because they look like a finder pattern
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores the code for the mask selection: runs of the same colour, 2x2 blocks,
+// finder-like patterns and an unbalanced share of dark modules
+func (c *Code) penalty() int {
+	result := 0
+	dark := 0
+	for i := range c.size {
+		result += c.linePenalty(func(j int) bool { return c.modules[i][j] })
+		result += c.linePenalty(func(j int) bool { return c.modules[j][i] })
+	}
+	for y := range c.size {
+		for x := range c.size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.size && y+1 < c.size {
+				color := c.modules[y][x]
+				if color == c.modules[y][x+1] && color == c.modules[y+1][x] && color == c.modules[y+1][x+1] {
+					result += penaltyBlock
+				}
+			}
+		}
+	}
+
+	total := c.size * c.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return result + k*penaltyBalance
+}
+
+// linePenalty scores the runs and finder-like patterns of one row or column
+func (c *Code) linePenalty(module func(i int) bool) int {
+	result := 0
+	run := 1
+	for i := 1; i <= c.size; i++ {
+		if i < c.size && module(i) == module(i-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			result += penaltyRun + run - 5
+		}
+		run = 1
+	}
+
+	for i := 0; i+len(finderLike[0]) <= c.size; i++ {
+		for _, pattern := range finderLike {
+			matches := true
+			for j, dark := range pattern {
+				if module(i+j) != dark {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				result += penaltyFinder
+			}
+		}
+	}
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
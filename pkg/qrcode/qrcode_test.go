@@ -0,0 +1,138 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncodeReference(t *testing.T) {
+	// Reference symbol for "wireguard" at level M (version 1, mask 5)
+	want := []string{
+		"#######.###.#.#######",
+		"#.....#.#.###.#.....#",
+		"#.###.#..#..#.#.###.#",
+		"#.###.#.#...#.#.###.#",
+		"#.###.#..###..#.###.#",
+		"#.....#....##.#.....#",
+		"#######.#.#.#.#######",
+		"........#####........",
+		"#.##.###.####.#..#.##",
+		"..#.##...#.##...#...#",
+		".#.######..#...#...##",
+		".##..#.#..##...###..#",
+		"..##.###....#.##.#.#.",
+		"........##.#.##.##.#.",
+		"#######.#.####.##.#..",
+		"#.....#.#.#......###.",
+		"#.###.#..#..#.##.##..",
+		"#.###.#.####..##.#.#.",
+		"#.###.#.#.#.#.#..##..",
+		"#.....#...#..#.##...#",
+		"#######.#....#.##.#..",
+	}
+
+	code, err := Encode([]byte("wireguard"), Medium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if code.Version != 1 || code.Size() != len(want) {
+		t.Fatalf("Expected version 1 with %d modules, got version %d with %d", len(want), code.Version, code.Size())
+	}
+	for y, row := range want {
+		for x, module := range row {
+			if code.Dark(x, y) != (module == '#') {
+				t.Errorf("Module %d,%d: expected %c", x, y, module)
+			}
+		}
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		version int
+		level   Level
+		want    int
+	}{
+		{1, Low, 17},
+		{1, Medium, 14},
+		{1, Quartile, 11},
+		{1, High, 7},
+		{10, Medium, 213},
+		{20, Low, 858},
+		{40, Low, 2953},
+		{40, Medium, 2331},
+		{40, Quartile, 1663},
+		{40, High, 1273},
+	}
+
+	for _, tt := range tests {
+		if got := Capacity(tt.version, tt.level); got != tt.want {
+			t.Errorf("Capacity(%d, %s) = %d, want %d", tt.version, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeVersions(t *testing.T) {
+	for _, level := range []Level{Low, Medium, Quartile, High} {
+		for _, length := range []int{0, Capacity(1, level), Capacity(1, level) + 1, Capacity(MaxVersion, level)} {
+			code, err := Encode(bytes.Repeat([]byte("x"), length), level)
+			if err != nil {
+				t.Fatalf("Encode(%d bytes, %s) failed: %v", length, level, err)
+			}
+			if Capacity(code.Version, level) < length || (code.Version > 1 && Capacity(code.Version-1, level) >= length) {
+				t.Errorf("%d bytes at %s: version %d is not the smallest that fits", length, level, code.Version)
+			}
+			if code.Size() != code.Version*4+17 {
+				t.Errorf("Version %d: expected %d modules, got %d", code.Version, code.Version*4+17, code.Size())
+			}
+		}
+
+		_, err := Encode(bytes.Repeat([]byte("x"), Capacity(MaxVersion, level)+1), level)
+		var tooLong *TooLongError
+		if !errors.As(err, &tooLong) || tooLong.Capacity != Capacity(MaxVersion, level) {
+			t.Errorf("Expected a TooLongError at %s, got %v", level, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	code, err := Encode([]byte("wireguard"), Medium)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	side := code.Size() + 2*QuietZone
+
+	content, err := code.PNG(4)
+	if err != nil {
+		t.Fatalf("PNG failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Invalid PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != side*4 || bounds.Dy() != side*4 {
+		t.Errorf("Expected a %dx%d image, got %v", side*4, side*4, bounds)
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("Expected a light quiet zone")
+	}
+	if r, _, _, _ := img.At(QuietZone*4, QuietZone*4).RGBA(); r != 0 {
+		t.Error("Expected the finder pattern corner to be dark")
+	}
+
+	svg := string(code.SVG(4))
+	if !strings.Contains(svg, `viewBox="0 0 29 29" width="116" height="116"`) || !strings.Contains(svg, `d="M4,4h1v1h-1z`) {
+		t.Errorf("Unexpected SVG:\n%s", svg)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(code.Terminal(), "\n"), "\n")
+	if len(lines) != (side+1)/2 {
+		t.Errorf("Expected %d terminal lines, got %d", (side+1)/2, len(lines))
+	}
+	if !strings.Contains(lines[QuietZone/2], "█▀▀▀▀▀█") {
+		t.Errorf("Expected the top finder pattern row in line %d, got %q", QuietZone/2, lines[QuietZone/2])
+	}
+}
@@ -0,0 +1,90 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// QuietZone is the width of the light border around the code, in modules
+const QuietZone = 4
+
+// Terminal renders the code with UTF-8 half blocks, two rows of modules per line. The colours
+// are set explicitly (black on white), so the code scans on dark and light terminals alike.
+func (c *Code) Terminal() string {
+	const (
+		start = "\x1b[30;47m"
+		reset = "\x1b[0m"
+	)
+
+	var sb strings.Builder
+	for y := -QuietZone; y < c.size+QuietZone; y += 2 {
+		sb.WriteString(start)
+		for x := -QuietZone; x < c.size+QuietZone; x++ {
+			top, bottom := c.Dark(x, y), c.Dark(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(reset + "\n")
+	}
+	return sb.String()
+}
+
+// PNG renders the code as a grayscale PNG with scale pixels per module
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("invalid scale: %d", scale)
+	}
+
+	side := (c.size + 2*QuietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for py := range side {
+		for px := range side {
+			shade := color.Gray{Y: 0xFF}
+			if c.Dark(px/scale-QuietZone, py/scale-QuietZone) {
+				shade = color.Gray{Y: 0x00}
+			}
+			img.SetGray(px, py, shade)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the code as an SVG image with scale units per module
+func (c *Code) SVG(scale int) []byte {
+	side := c.size + 2*QuietZone
+
+	var path strings.Builder
+	for y := range c.size {
+		for x := range c.size {
+			if c.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">
+<rect width="100%%" height="100%%" fill="#ffffff"/>
+<path fill="#000000" d="%s"/>
+</svg>
+`, side, side, side*scale, side*scale, path.String())
+	return buf.Bytes()
+}
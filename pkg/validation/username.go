@@ -20,6 +20,20 @@ func IsValidCountryCode(code string) bool {
 	return len(code) == 2 && isAlpha(code)
 }
 
+// IsValidInterfaceName checks if a network interface name is valid on Linux
+// (at most 15 characters: letters, digits, '-', '_' and '.').
+func IsValidInterfaceName(name string) bool {
+	if name == "" || len(name) > 15 || name == "." || name == ".." {
+		return false
+	}
+	for _, r := range name {
+		if !isAlpha(string(r)) && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
 // isAlpha checks if a string contains only alphabetic characters.
 func isAlpha(s string) bool {
 	for _, r := range s {
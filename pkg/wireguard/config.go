@@ -2,11 +2,11 @@
 package wireguard
 
 import (
-	"bytes"
 	"fmt"
 	"os"
+	"os/user"
+	"strconv"
 	"strings"
-	"text/template"
 	"time"
 
 	"protonvpn-wg-confgen/internal/api"
@@ -14,95 +14,199 @@ import (
 	"protonvpn-wg-confgen/internal/constants"
 )
 
-// wireguardConfigTemplate is the template for generating WireGuard configuration
-const wireguardConfigTemplate = `[Interface]
-PrivateKey = {{.PrivateKey}}
-{{.AddressLine}}
-DNS = {{.DNS}}
+// metadataTimeFormat is the format of times in the metadata header
+const metadataTimeFormat = "2006-01-02 15:04:05 MST"
 
-[Peer]
-PublicKey = {{.PublicKey}}
-AllowedIPs = {{.AllowedIPs}}
-Endpoint = {{.Endpoint}}:{{.Port}}
-`
-
-// configData holds the data for the WireGuard config template
+// configData holds the data every output format is rendered from
 type configData struct {
 	PrivateKey  string
-	AddressLine string
-	DNS         string
+	Addresses   []string
+	DNS         []string
 	PublicKey   string
-	AllowedIPs  string
+	AllowedIPs  []string
 	Endpoint    string
 	Port        int
+	Interface   string // Interface name for formats that create the interface
+	DeviceIface string // Interface name with the -devices suffix, for formats imported side by side on one host
+	Autoconnect bool   // Whether the network manager brings the interface up by itself
+	Firewall    bool   // Whether router formats add a firewall zone for the interface
+	SecretKey   string // Key of the configuration in the Kubernetes Secret
+	Listeners   []config.ProxyListener
+
+	Metadata       string // Comment header for formats that support comments
+	Generated      time.Time
+	DeviceName     string
+	KeyProfile     string // Key store profile, identifying the device across runs
+	CertFeatures   []string
+	CertExpiry     time.Time
+	Server         *api.LogicalServer
+	PhysicalServer *api.PhysicalServer
+	Group          []GroupServer // Further servers of the proxy formats' selectable group
+}
+
+// GroupServer is a server added to the selectable group of the proxy formats
+type GroupServer struct {
+	Server         *api.LogicalServer
+	PhysicalServer *api.PhysicalServer
 }
 
 // ConfigGenerator generates WireGuard configuration files
 type ConfigGenerator struct {
-	config   *config.Config
-	template *template.Template
+	config       *config.Config
+	certFeatures []string
+	certExpiry   time.Time
+	group        []GroupServer
 }
 
 // NewConfigGenerator creates a new configuration generator
 func NewConfigGenerator(cfg *config.Config) *ConfigGenerator {
-	tmpl := template.Must(template.New("wireguard").Parse(wireguardConfigTemplate))
 	return &ConfigGenerator{
-		config:   cfg,
-		template: tmpl,
+		config: cfg,
 	}
 }
 
-// Generate creates a WireGuard configuration file
+// SetCertificateFeatures sets the certificate features listed in the metadata header
+func (g *ConfigGenerator) SetCertificateFeatures(features []string) {
+	g.certFeatures = features
+}
+
+// SetCertificateExpiry sets the certificate expiration time listed in the metadata header.
+// The header is not updated when -renew re-registers the key of an unchanged config.
+func (g *ConfigGenerator) SetCertificateExpiry(expiresAt time.Time) {
+	g.certExpiry = expiresAt
+}
+
+// SetGroupServers sets the servers added after the selected one to the selectable group of
+// the proxy formats; the other formats only use the selected server
+func (g *ConfigGenerator) SetGroupServers(servers []GroupServer) {
+	g.group = servers
+}
+
+// Generate writes the configuration in every output format selected with -format
 func (g *ConfigGenerator) Generate(server *api.LogicalServer, physicalServer *api.PhysicalServer, privateKey string) error {
-	content, err := g.buildConfig(server, physicalServer, privateKey)
+	outputs, err := ResolveOutputs(g.config)
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(g.config.OutputFile, []byte(content), 0o600); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	data := g.newConfigData(server, physicalServer, privateKey)
+	for _, output := range outputs {
+		files, err := renderFiles(outputFormats[output.Format], data, output.Path)
+		if err != nil {
+			return fmt.Errorf("failed to render %s output: %w", output.Format, err)
+		}
+		for _, file := range files {
+			if err := writeOutputFile(&file); err != nil {
+				return fmt.Errorf("failed to write %s output: %w", output.Format, err)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (g *ConfigGenerator) buildConfig(server *api.LogicalServer, physicalServer *api.PhysicalServer, privateKey string) (string, error) {
-	// Build metadata header
-	metadata := g.buildMetadata(server, physicalServer)
+// renderFiles renders an output format into the files written to path
+func renderFiles(format outputFormat, data *configData, path string) ([]outputFile, error) {
+	if format.files != nil {
+		return format.files(data, strings.TrimSuffix(path, format.suffix))
+	}
 
-	data := configData{
-		PrivateKey:  privateKey,
-		AddressLine: g.buildAddressLine(),
-		DNS:         strings.Join(g.config.DNSServers, ", "),
-		PublicKey:   physicalServer.X25519PublicKey,
-		AllowedIPs:  strings.Join(g.config.AllowedIPs, ", "),
-		Endpoint:    physicalServer.EntryIP,
-		Port:        constants.WireGuardPort,
+	content, err := format.render(data)
+	if err != nil {
+		return nil, err
 	}
+	return []outputFile{{path: path, content: content, mode: 0o600}}, nil
+}
 
-	var buf bytes.Buffer
-	if err := g.template.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+// writeOutputFile writes a file with its mode, also when it already exists with another one
+func writeOutputFile(file *outputFile) error {
+	if err := os.WriteFile(file.path, file.content, file.mode); err != nil {
+		return err
 	}
+	if err := os.Chmod(file.path, file.mode); err != nil {
+		return err
+	}
+	if file.group != "" && os.Geteuid() == 0 {
+		// Only root can hand the file to a service group; otherwise the README documents the manual step
+		if group, err := user.LookupGroup(file.group); err == nil {
+			if gid, err := strconv.Atoi(group.Gid); err == nil {
+				return os.Chown(file.path, -1, gid)
+			}
+		}
+	}
+	return nil
+}
+
+// Render returns the configuration in the named output format
+func (g *ConfigGenerator) Render(format string, server *api.LogicalServer, physicalServer *api.PhysicalServer, privateKey string) ([]byte, error) {
+	outputFormat, ok := outputFormats[format]
+	if !ok {
+		return nil, unknownFormatError(format)
+	}
+	if outputFormat.render == nil {
+		return nil, fmt.Errorf("output format %s writes several files and cannot be rendered as one", format)
+	}
+	return outputFormat.render(g.newConfigData(server, physicalServer, privateKey))
+}
 
-	return metadata + buf.String(), nil
+// buildConfig returns the wg-quick configuration
+func (g *ConfigGenerator) buildConfig(server *api.LogicalServer, physicalServer *api.PhysicalServer, privateKey string) (string, error) {
+	content, err := g.Render(FormatWGQuick, server, physicalServer, privateKey)
+	return string(content), err
 }
 
-func (g *ConfigGenerator) buildAddressLine() string {
+func (g *ConfigGenerator) newConfigData(server *api.LogicalServer, physicalServer *api.PhysicalServer, privateKey string) *configData {
+	now := time.Now()
+	return &configData{
+		PrivateKey:     privateKey,
+		Addresses:      g.addresses(),
+		DNS:            g.config.DNSServers,
+		PublicKey:      physicalServer.X25519PublicKey,
+		AllowedIPs:     g.config.AllowedIPs,
+		Endpoint:       physicalServer.EntryIP,
+		Port:           constants.WireGuardPort,
+		Interface:      g.config.InterfaceName,
+		DeviceIface:    g.config.DeviceInterfaceName(),
+		Autoconnect:    g.config.NMAutoconnect,
+		Firewall:       g.config.FirewallZone,
+		SecretKey:      g.config.SecretKey,
+		Listeners:      g.config.ProxyListeners,
+		Metadata:       g.buildMetadata(server, physicalServer, now),
+		Generated:      now,
+		DeviceName:     g.config.DeviceName,
+		KeyProfile:     g.config.KeyProfileName(),
+		CertFeatures:   g.certFeatures,
+		CertExpiry:     g.certExpiry,
+		Server:         server,
+		PhysicalServer: physicalServer,
+		Group:          g.group,
+	}
+}
+
+func (g *ConfigGenerator) addresses() []string {
 	if g.config.EnableIPv6 {
-		return fmt.Sprintf("Address = %s, %s", constants.WireGuardIPv4, constants.WireGuardIPv6)
+		return []string{constants.WireGuardIPv4, constants.WireGuardIPv6}
 	}
-	return fmt.Sprintf("Address = %s", constants.WireGuardIPv4)
+	return []string{constants.WireGuardIPv4}
 }
 
-func (g *ConfigGenerator) buildMetadata(server *api.LogicalServer, physicalServer *api.PhysicalServer) string {
+// buildMetadata returns the comment header describing the configuration when it was generated.
+// It is informational only: -renew leaves an unchanged config (and its header) alone when it
+// re-registers the key, so the certificate expiry must be read from the key store when available.
+func (g *ConfigGenerator) buildMetadata(server *api.LogicalServer, physicalServer *api.PhysicalServer, generated time.Time) string {
 	var metadata strings.Builder
 
 	metadata.WriteString("# ProtonVPN WireGuard Configuration\n")
-	metadata.WriteString(fmt.Sprintf("# Generated: %s\n", time.Now().Format("2006-01-02 15:04:05 MST")))
+	metadata.WriteString(fmt.Sprintf("# Generated: %s\n", generated.Format(metadataTimeFormat)))
 	if g.config.DeviceName != "" {
 		metadata.WriteString(fmt.Sprintf("# Device: %s\n", g.config.DeviceName))
 	}
+	if len(g.certFeatures) > 0 {
+		metadata.WriteString(fmt.Sprintf("# Certificate Features: %s\n", strings.Join(g.certFeatures, ", ")))
+	}
+	if !g.certExpiry.IsZero() {
+		metadata.WriteString(fmt.Sprintf("# Certificate Expires: %s\n", g.certExpiry.UTC().Format(metadataTimeFormat)))
+	}
 	metadata.WriteString("#\n")
 	metadata.WriteString("# Server Information:\n")
 	metadata.WriteString(fmt.Sprintf("# - Name: %s\n", server.Name))
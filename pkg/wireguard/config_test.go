@@ -1,8 +1,16 @@
 package wireguard
 
 import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"protonvpn-wg-confgen/internal/api"
 	"protonvpn-wg-confgen/internal/config"
@@ -117,3 +125,609 @@ func TestConfigGenerationWithIPv6(t *testing.T) {
 		t.Errorf("Expected both IPv4 and IPv6 in AllowedIPs, got:\n%s", result)
 	}
 }
+
+func TestConfigGenerationCertificateFeatures(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []string{"10.2.0.1"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+	}
+
+	generator := NewConfigGenerator(cfg)
+	generator.SetCertificateFeatures(api.GetCertificateFeatureNames(api.CertificateFeatures{NetshieldLevel: 2, PortForwarding: true}))
+
+	result, err := generator.buildConfig(&api.LogicalServer{Name: "Test-Server"}, &api.PhysicalServer{}, "key")
+	if err != nil {
+		t.Fatalf("buildConfig failed: %v", err)
+	}
+
+	if !strings.Contains(result, "# Certificate Features: NetShield (level 2), Port Forwarding\n") {
+		t.Errorf("Expected certificate features in metadata\nGot:\n%s", result)
+	}
+}
+
+func TestParseConfigRoundTrip(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []string{"10.2.0.1"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+		DeviceName: "router",
+	}
+
+	generator := NewConfigGenerator(cfg)
+	generator.SetCertificateFeatures([]string{"NetShield (level 1)", "VPN Accelerator"})
+	expiresAt := time.Date(2027, 10, 18, 12, 30, 0, 0, time.UTC)
+	generator.SetCertificateExpiry(expiresAt)
+
+	server := &api.LogicalServer{
+		Name: "CH-US#1", ExitCountry: "US", EntryCountry: "CH", City: "New York",
+		Tier: api.TierPlus, Load: 42, Score: 1.25, Features: api.FeatureSecureCore | api.FeatureP2P,
+	}
+	physicalServer := &api.PhysicalServer{
+		ID: "phys-1", EntryIP: "192.168.1.1", ExitIP: "192.168.1.2", X25519PublicKey: "serverKey=",
+	}
+
+	content, err := generator.buildConfig(server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("buildConfig failed: %v", err)
+	}
+
+	parsed, err := ParseConfig([]byte(content))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	checks := map[string][2]string{
+		"Device":           {parsed.Device, "router"},
+		"ServerName":       {parsed.ServerName, "CH-US#1"},
+		"Country":          {parsed.Country, "US"},
+		"City":             {parsed.City, "New York"},
+		"PhysicalServerID": {parsed.PhysicalServerID, "phys-1"},
+		"ExitIP":           {parsed.ExitIP, "192.168.1.2"},
+		"SecureCore":       {parsed.SecureCore, "CH → US"},
+		"PrivateKey":       {parsed.PrivateKey, "clientKey="},
+		"PublicKey":        {parsed.PublicKey, "serverKey="},
+		"Endpoint":         {parsed.Endpoint, "192.168.1.1:51820"},
+		"Features":         {strings.Join(parsed.CertificateFeatures, "|"), "NetShield (level 1)|VPN Accelerator"},
+	}
+	for field, check := range checks {
+		if check[0] != check[1] {
+			t.Errorf("%s: expected %q, got %q", field, check[1], check[0])
+		}
+	}
+
+	if !parsed.CertificateExpires.Equal(expiresAt) {
+		t.Errorf("Expected certificate expiry %v, got %v", expiresAt, parsed.CertificateExpires)
+	}
+	if parsed.Load != 42 || parsed.Score != 1.25 || parsed.Generated.IsZero() {
+		t.Errorf("Unexpected load, score or generation time: %d, %.2f, %v", parsed.Load, parsed.Score, parsed.Generated)
+	}
+}
+
+func TestResolveOutputs(t *testing.T) {
+	cfg := &config.Config{
+		OutputFile: "proton.conf",
+		Outputs:    []config.Output{{Format: FormatWGQuick}, {Format: FormatWG}, {Format: FormatJSON, Path: "out.json"}},
+	}
+
+	paths, err := OutputPaths(cfg)
+	if err != nil {
+		t.Fatalf("OutputPaths failed: %v", err)
+	}
+	if strings.Join(paths, "|") != "proton.conf|proton.setconf|out.json" {
+		t.Errorf("Unexpected output paths: %v", paths)
+	}
+
+	cfg.Outputs = []config.Output{{Format: "yaml"}}
+	if _, err := ResolveOutputs(cfg); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+
+	cfg.Outputs = []config.Output{{Format: FormatWGQuick}, {Format: FormatWG, Path: "proton.conf"}}
+	if _, err := ResolveOutputs(cfg); err == nil {
+		t.Error("Expected two outputs to the same file to be rejected")
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []string{"10.2.0.1"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+		DeviceName: "router",
+	}
+	generator := NewConfigGenerator(cfg)
+	generator.SetCertificateFeatures([]string{"VPN Accelerator"})
+
+	server := &api.LogicalServer{Name: "CH#1", ExitCountry: "CH", City: "Zurich"}
+	physicalServer := &api.PhysicalServer{ID: "phys-1", EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	wg, err := generator.Render(FormatWG, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render wg failed: %v", err)
+	}
+	if strings.Contains(string(wg), "Address =") || strings.Contains(string(wg), "DNS =") {
+		t.Errorf("Expected no wg-quick keys in wg output, got:\n%s", wg)
+	}
+	if !strings.Contains(string(wg), "Endpoint = 192.168.1.1:51820") {
+		t.Errorf("Expected the endpoint in wg output, got:\n%s", wg)
+	}
+
+	content, err := generator.Render(FormatJSON, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render json failed: %v", err)
+	}
+	var doc jsonConfig
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, content)
+	}
+	if doc.Device != "router" || doc.Interface.PrivateKey != "clientKey=" || doc.Peer.Endpoint != "192.168.1.1:51820" ||
+		doc.Server.PhysicalServerID != "phys-1" || doc.Certificate == nil || doc.Certificate.Features[0] != "VPN Accelerator" {
+		t.Errorf("Unexpected JSON output:\n%s", content)
+	}
+}
+
+func TestNetworkdFiles(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		DNSServers:    []string{"10.2.0.1"},
+		AllowedIPs:    []string{"0.0.0.0/0", "::/0"},
+		EnableIPv6:    true,
+		OutputFile:    filepath.Join(dir, "protonvpn.conf"),
+		Outputs:       []config.Output{{Format: FormatNetworkd}},
+		InterfaceName: "wg-proton",
+	}
+
+	paths, err := OutputPaths(cfg)
+	if err != nil || len(paths) != 1 || paths[0] != filepath.Join(dir, "protonvpn.netdev") {
+		t.Fatalf("Expected protonvpn.netdev, got %v, %v", paths, err)
+	}
+
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+	if err := generator.Generate(server, physicalServer, "clientKey="); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	key, err := os.ReadFile(filepath.Join(dir, "protonvpn.key"))
+	if err != nil || string(key) != "clientKey=\n" {
+		t.Errorf("Unexpected key file: %q, %v", key, err)
+	}
+
+	netdev, _ := os.ReadFile(filepath.Join(dir, "protonvpn.netdev"))
+	for _, expected := range []string{
+		"Name=wg-proton\nKind=wireguard\n",
+		"PrivateKeyFile=" + filepath.Join(dir, "protonvpn.key") + "\n",
+		"RouteTable=51820\n",
+		"AllowedIPs=0.0.0.0/0,::/0\n",
+		"Endpoint=192.168.1.1:51820\n",
+	} {
+		if !strings.Contains(string(netdev), expected) {
+			t.Errorf("Expected .netdev to contain %q\nGot:\n%s", expected, netdev)
+		}
+	}
+	if strings.Contains(string(netdev), "clientKey=") {
+		t.Error("Expected the private key to stay out of the .netdev file")
+	}
+
+	network, _ := os.ReadFile(filepath.Join(dir, "protonvpn.network"))
+	for _, expected := range []string{
+		"Address=10.2.0.2/32\nAddress=2a07:b944::2:2/128\n",
+		"DNS=10.2.0.1\nDomains=~.\n",
+		"Family=both\nFirewallMark=51820\nInvertRule=yes\nTable=51820\n",
+		"SuppressPrefixLength=0\n",
+	} {
+		if !strings.Contains(string(network), expected) {
+			t.Errorf("Expected .network to contain %q\nGot:\n%s", expected, network)
+		}
+	}
+}
+
+func TestDefaultRouteFamily(t *testing.T) {
+	tests := map[string]string{
+		"0.0.0.0/0":           "ipv4",
+		"::/0":                "ipv6",
+		"0.0.0.0/0,::/0":      "both",
+		"10.0.0.0/8,fd00::/8": "",
+	}
+	for allowedIPs, want := range tests {
+		if got := defaultRouteFamily(strings.Split(allowedIPs, ",")); got != want {
+			t.Errorf("%s: expected %q, got %q", allowedIPs, want, got)
+		}
+	}
+}
+
+func TestNetworkManagerKeepsUUID(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		DNSServers:      []string{"10.2.0.1", "2a07:b944::2:1"},
+		AllowedIPs:      []string{"0.0.0.0/0", "::/0"},
+		EnableIPv6:      true,
+		OutputFile:      "protonvpn.conf",
+		Outputs:         []config.Output{{Format: FormatNetworkManager}},
+		NMConnectionDir: dir,
+		NMAutoconnect:   true,
+	}
+	path := filepath.Join(dir, "protonvpn.nmconnection")
+
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+	if err := generator.Generate(server, physicalServer, "clientKey="); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", path, err)
+	}
+	for _, expected := range []string{
+		"id=protonvpn\n",
+		"type=wireguard\ninterface-name=protonvpn\nautoconnect=true\n",
+		"[wireguard-peer.serverKey=]\nendpoint=192.168.1.1:51820\nallowed-ips=0.0.0.0/0;::/0;\n",
+		"address1=10.2.0.2/32\ndns=10.2.0.1;\ndns-priority=-50\ndns-search=~.;\n",
+		"address1=2a07:b944::2:2/128\ndns=2a07:b944::2:1;\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected keyfile to contain %q\nGot:\n%s", expected, content)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	uuid, _ := existingConnectionUUID(path)
+	if len(uuid) != 36 {
+		t.Fatalf("Expected a UUID, got %q", uuid)
+	}
+
+	physicalServer.EntryIP = "192.168.1.2"
+	if err := generator.Generate(server, physicalServer, "rotatedKey="); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	rotated, _ := existingConnectionUUID(path)
+	if rotated != uuid {
+		t.Errorf("Expected the UUID %s to be kept across rotations, got %s", uuid, rotated)
+	}
+
+	// With -devices, every keyfile gets its own interface
+	cfg.InterfaceName = "wg"
+	cfg.Devices = []string{"tv"}
+	if err := NewConfigGenerator(cfg.DeviceConfigs()[0]).Generate(server, physicalServer, "clientKey="); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	content, err = os.ReadFile(filepath.Join(dir, "protonvpn-tv.nmconnection"))
+	if err != nil || !strings.Contains(string(content), "interface-name=wg-tv\n") {
+		t.Errorf("Expected the device suffix in interface-name, got err=%v\n%s", err, content)
+	}
+}
+
+func TestRenderOpenWrt(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers:    []string{"10.2.0.1"},
+		AllowedIPs:    []string{"0.0.0.0/0"},
+		InterfaceName: "proton",
+		FirewallZone:  true,
+	}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	content, err := generator.Render(FormatOpenWrt, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "#!/bin/sh\n# ProtonVPN WireGuard Configuration") {
+		t.Errorf("Expected a shell script with the metadata header, got:\n%s", content)
+	}
+	for _, expected := range []string{
+		"delete network.proton\nset network.proton=interface\nset network.proton.proto='wireguard'\n",
+		"set network.proton.private_key='clientKey='\n",
+		"add_list network.proton.addresses='10.2.0.2/32'\n",
+		"delete network.proton_peer\nset network.proton_peer=wireguard_proton\n",
+		"set network.proton_peer.endpoint_host='192.168.1.1'\n",
+		"set firewall.proton=zone\n",
+		"set firewall.proton_lan.dest='proton'\n",
+		"commit firewall\nEOF\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected openwrt output to contain %q\nGot:\n%s", expected, content)
+		}
+	}
+
+	cfg.InterfaceName = "proton-vpn"
+	if _, err := generator.Render(FormatOpenWrt, server, physicalServer, "clientKey="); err == nil {
+		t.Error("Expected an interface name with '-' to be rejected")
+	}
+	cfg.InterfaceName = "protonvpn_ch1"
+	if _, err := generator.Render(FormatOpenWrt, server, physicalServer, "clientKey="); err == nil {
+		t.Error("Expected a zone name longer than 11 characters to be rejected")
+	}
+}
+
+func TestRenderRouterOS(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers:    []string{"10.2.0.1"},
+		AllowedIPs:    []string{"0.0.0.0/0", "::/0"},
+		EnableIPv6:    true,
+		InterfaceName: "proton",
+	}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	content, err := generator.Render(FormatRouterOS, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, expected := range []string{
+		`[/interface wireguard find where comment="protonvpn-wg-confgen proton"]`,
+		`/interface wireguard set [find where comment="protonvpn-wg-confgen proton"] name="proton" private-key="clientKey="`,
+		`[/interface wireguard peers find where comment="protonvpn-wg-confgen proton"]`,
+		`endpoint-address=192.168.1.1 endpoint-port=51820 allowed-address=0.0.0.0/0,::/0`,
+		"/ip address remove [find where comment=\"protonvpn-wg-confgen proton\"]\n/ip address add address=10.2.0.2/32 interface=\"proton\"",
+		"/ipv6 address add address=2a07:b944::2:2/128 interface=\"proton\" advertise=no",
+		"/ip dns set servers=10.2.0.1\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected routeros output to contain %q\nGot:\n%s", expected, content)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, ":if ") && !strings.HasPrefix(line, "/") {
+			t.Errorf("Unexpected line in routeros output: %q", line)
+		}
+	}
+}
+
+func TestRenderFirewallImports(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers:    []string{"10.2.0.1"},
+		AllowedIPs:    []string{"0.0.0.0/0"},
+		InterfaceName: "tun_wg1",
+	}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	privateKey := base64.StdEncoding.EncodeToString(key.Bytes())
+	publicKey := base64.StdEncoding.EncodeToString(key.PublicKey().Bytes())
+
+	content, err := generator.Render(FormatOPNsense, server, physicalServer, privateKey)
+	if err != nil {
+		t.Fatalf("Render opnsense failed: %v", err)
+	}
+	var opnsense opnSenseConfig
+	if err := xml.Unmarshal(content, &opnsense); err != nil {
+		t.Fatalf("Invalid opnsense XML: %v\n%s", err, content)
+	}
+	if len(opnsense.WireGuard.Servers) != 1 || len(opnsense.WireGuard.Clients) != 1 {
+		t.Fatalf("Expected one instance and one peer, got:\n%s", content)
+	}
+	instance, peer := opnsense.WireGuard.Servers[0], opnsense.WireGuard.Clients[0]
+	if instance.PublicKey != publicKey || instance.TunnelAddress != "10.2.0.2/32" || instance.DNS != "10.2.0.1" {
+		t.Errorf("Unexpected instance: %+v", instance)
+	}
+	if instance.Peers != peer.UUID || peer.UUID == "" {
+		t.Errorf("Expected the instance to reference peer %q, got %q", peer.UUID, instance.Peers)
+	}
+	if peer.PublicKey != "serverKey=" || peer.ServerAddress != "192.168.1.1" || peer.ServerPort != 51820 || peer.TunnelAddress != "0.0.0.0/0" {
+		t.Errorf("Unexpected peer: %+v", peer)
+	}
+
+	// Re-rendering, even for another server, must replace the same instance and peer on import
+	again, err := generator.Render(FormatOPNsense, &api.LogicalServer{Name: "CH#2"}, physicalServer, privateKey)
+	if err != nil {
+		t.Fatalf("Render opnsense failed: %v", err)
+	}
+	var second opnSenseConfig
+	if err := xml.Unmarshal(again, &second); err != nil {
+		t.Fatalf("Invalid opnsense XML: %v\n%s", err, again)
+	}
+	if second.WireGuard.Servers[0].UUID != instance.UUID || second.WireGuard.Clients[0].UUID != peer.UUID || instance.UUID[14] != '5' {
+		t.Errorf("Expected stable version 5 UUIDs %s/%s, got %s/%s", instance.UUID, peer.UUID,
+			second.WireGuard.Servers[0].UUID, second.WireGuard.Clients[0].UUID)
+	}
+	cfg.KeyProfile = "office"
+	if other, err := generator.Render(FormatOPNsense, server, physicalServer, privateKey); err != nil || strings.Contains(string(other), instance.UUID) {
+		t.Errorf("Expected another key profile to get other UUIDs, got err=%v", err)
+	}
+
+	content, err = generator.Render(FormatPfSense, server, physicalServer, privateKey)
+	if err != nil {
+		t.Fatalf("Render pfsense failed: %v", err)
+	}
+	var pfsense pfSenseConfig
+	if err := xml.Unmarshal(content, &pfsense); err != nil {
+		t.Fatalf("Invalid pfsense XML: %v\n%s", err, content)
+	}
+	if len(pfsense.Tunnels) != 1 || len(pfsense.Peers) != 1 {
+		t.Fatalf("Expected one tunnel and one peer, got:\n%s", content)
+	}
+	tunnel, pfPeer := pfsense.Tunnels[0], pfsense.Peers[0]
+	if tunnel.Name != "tun_wg1" || tunnel.PublicKey != publicKey || len(tunnel.Addresses) != 1 ||
+		tunnel.Addresses[0] != (pfSensePrefix{Address: "10.2.0.2", Mask: "32"}) {
+		t.Errorf("Unexpected tunnel: %+v", tunnel)
+	}
+	if pfPeer.Tunnel != "tun_wg1" || len(pfPeer.AllowedIPs) != 1 || pfPeer.AllowedIPs[0] != (pfSensePrefix{Address: "0.0.0.0", Mask: "0"}) {
+		t.Errorf("Unexpected peer: %+v", pfPeer)
+	}
+
+	if _, err := generator.Render(FormatPfSense, server, physicalServer, "clientKey="); err == nil {
+		t.Error("Expected an invalid private key to be rejected")
+	}
+}
+
+func TestRenderContainerFormats(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []string{"10.2.0.1"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+		SecretKey:  "proton.conf",
+	}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	env, err := generator.Render(FormatGluetun, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render gluetun failed: %v", err)
+	}
+	compose, err := generator.Render(FormatCompose, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render compose failed: %v", err)
+	}
+	for _, variable := range []string{
+		"VPN_SERVICE_PROVIDER=custom",
+		"WIREGUARD_PRIVATE_KEY=clientKey=",
+		"WIREGUARD_ADDRESSES=10.2.0.2/32",
+		"WIREGUARD_PUBLIC_KEY=serverKey=",
+		"VPN_ENDPOINT_IP=192.168.1.1",
+		"VPN_ENDPOINT_PORT=51820",
+	} {
+		if !strings.Contains(string(env), "\n"+variable+"\n") {
+			t.Errorf("Expected gluetun output to contain %q\nGot:\n%s", variable, env)
+		}
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.Contains(string(compose), "      "+name+": \""+value+"\"\n") {
+			t.Errorf("Expected compose output to contain %s\nGot:\n%s", variable, compose)
+		}
+	}
+
+	secret, err := generator.Render(FormatKubernetes, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render kubernetes failed: %v", err)
+	}
+	for _, expected := range []string{
+		"kind: Secret\nmetadata:\n  name: protonvpn\n",
+		"stringData:\n  proton.conf: |\n    # ProtonVPN WireGuard Configuration\n",
+		"\n    [Interface]\n    PrivateKey = clientKey=\n",
+	} {
+		if !strings.Contains(string(secret), expected) {
+			t.Errorf("Expected kubernetes output to contain %q\nGot:\n%s", expected, secret)
+		}
+	}
+
+	cfg.InterfaceName = "Proton_VPN"
+	if _, err := generator.Render(FormatKubernetes, server, physicalServer, "clientKey="); err == nil {
+		t.Error("Expected an invalid Secret name to be rejected")
+	}
+}
+
+func TestRenderProxyFormats(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []string{"10.2.0.1"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+	}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+	generator.SetGroupServers([]GroupServer{{
+		Server:         &api.LogicalServer{Name: "CH#2"},
+		PhysicalServer: &api.PhysicalServer{EntryIP: "192.168.1.2", X25519PublicKey: "otherKey="},
+	}})
+
+	content, err := generator.Render(FormatSingBox, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render sing-box failed: %v", err)
+	}
+	var singBox singBoxConfig
+	if err := json.Unmarshal(content, &singBox); err != nil {
+		t.Fatalf("Invalid sing-box JSON: %v\n%s", err, content)
+	}
+	if len(singBox.Endpoints) != 2 || singBox.Endpoints[1].Tag != "proton-ch-2" || singBox.Endpoints[1].Peers[0].Address != "192.168.1.2" {
+		t.Errorf("Unexpected sing-box endpoints: %+v", singBox.Endpoints)
+	}
+	if len(singBox.Outbounds) != 1 || strings.Join(singBox.Outbounds[0].Outbounds, ",") != "proton-ch-1,proton-ch-2" {
+		t.Errorf("Unexpected sing-box selector: %+v", singBox.Outbounds)
+	}
+
+	content, err = generator.Render(FormatXray, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render xray failed: %v", err)
+	}
+	var xray xrayConfig
+	if err := json.Unmarshal(content, &xray); err != nil {
+		t.Fatalf("Invalid xray JSON: %v\n%s", err, content)
+	}
+	if len(xray.Outbounds) != 2 || xray.Outbounds[0].Settings.SecretKey != "clientKey=" || xray.Outbounds[0].Settings.Peers[0].Endpoint != "192.168.1.1:51820" {
+		t.Errorf("Unexpected xray outbounds: %+v", xray.Outbounds)
+	}
+	if xray.Outbounds[0].Tag != "proton-ch-1" || strings.Contains(string(content), "balancer") {
+		t.Errorf("Expected the selected server as the first (default) outbound and no balancer, got %s", content)
+	}
+
+	content, err = generator.Render(FormatMihomo, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render mihomo failed: %v", err)
+	}
+	for _, expected := range []string{
+		"proxies:\n  - name: \"proton-ch-1\"\n    type: wireguard\n    server: 192.168.1.1\n    port: 51820\n    ip: 10.2.0.2\n",
+		"    public-key: \"otherKey=\"\n    allowed-ips: [\"0.0.0.0/0\"]\n",
+		"    dns: [\"10.2.0.1\"]\n",
+		"proxy-groups:\n  - name: \"proton\"\n    type: select\n    proxies:\n      - \"proton-ch-1\"\n      - \"proton-ch-2\"\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected mihomo output to contain %q\nGot:\n%s", expected, content)
+		}
+	}
+
+	generator.SetGroupServers(nil)
+	content, err = generator.Render(FormatMihomo, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render mihomo failed: %v", err)
+	}
+	if strings.Contains(string(content), "proxy-groups:") {
+		t.Errorf("Expected no group for a single server, got:\n%s", content)
+	}
+}
+
+func TestRenderWireproxy(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []string{"10.2.0.1"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+		ProxyListeners: []config.ProxyListener{
+			{Type: "socks5", Address: "127.0.0.1:1080"},
+			{Type: "http", Address: "127.0.0.1:8080"},
+		},
+	}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	content, err := generator.Render(FormatWireproxy, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	expected := "[Interface]\nPrivateKey = clientKey=\nAddress = 10.2.0.2/32\nDNS = 10.2.0.1\n\n" +
+		"[Peer]\nPublicKey = serverKey=\nAllowedIPs = 0.0.0.0/0\nEndpoint = 192.168.1.1:51820\n\n" +
+		"[Socks5]\nBindAddress = 127.0.0.1:1080\n\n[http]\nBindAddress = 127.0.0.1:8080\n"
+	if !strings.HasSuffix(string(content), expected) {
+		t.Errorf("Expected wireproxy output to end with:\n%s\nGot:\n%s", expected, content)
+	}
+
+	cfg.ProxyListeners = nil
+	if _, err := generator.Render(FormatWireproxy, server, physicalServer, "clientKey="); err == nil {
+		t.Error("Expected wireproxy output without listeners to be rejected")
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	cfg := &config.Config{DNSServers: []string{"10.2.0.1"}, AllowedIPs: []string{"0.0.0.0/0"}}
+	generator := NewConfigGenerator(cfg)
+	server := &api.LogicalServer{Name: "CH#1"}
+	physicalServer := &api.PhysicalServer{EntryIP: "192.168.1.1", X25519PublicKey: "serverKey="}
+
+	content, err := generator.Render(FormatWGQuick, server, physicalServer, "clientKey=")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	stripped := string(StripComments(content))
+	if !strings.HasPrefix(stripped, "[Interface]\nPrivateKey = clientKey=\n") || strings.Contains(stripped, "#") {
+		t.Errorf("Expected the configuration without comments, got:\n%s", stripped)
+	}
+	if !strings.HasSuffix(stripped, "Endpoint = 192.168.1.1:51820\n") {
+		t.Errorf("Expected the peer to be kept, got:\n%s", stripped)
+	}
+}
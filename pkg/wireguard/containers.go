@@ -0,0 +1,121 @@
+package wireguard
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// gluetunImage is the image used by the docker-compose service
+const gluetunImage = "qmcgaw/gluetun"
+
+// envVar is one environment variable of the gluetun container
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// gluetunEnv returns the variables that configure gluetun's custom WireGuard provider
+func gluetunEnv(data *configData) []envVar {
+	return []envVar{
+		{"VPN_SERVICE_PROVIDER", "custom"},
+		{"VPN_TYPE", "wireguard"},
+		{"WIREGUARD_PRIVATE_KEY", data.PrivateKey},
+		{"WIREGUARD_ADDRESSES", strings.Join(data.Addresses, ",")},
+		{"WIREGUARD_PUBLIC_KEY", data.PublicKey},
+		{"WIREGUARD_ALLOWED_IPS", strings.Join(data.AllowedIPs, ",")},
+		{"VPN_ENDPOINT_IP", data.Endpoint},
+		{"VPN_ENDPOINT_PORT", strconv.Itoa(data.Port)},
+	}
+}
+
+// gluetunTemplate is the template for env files, as read by docker run --env-file and env_file:
+var gluetunTemplate = template.Must(template.New("gluetun").Parse(`{{range .}}{{.Name}}={{.Value}}
+{{end}}`))
+
+// composeTemplate is the template for the docker-compose service. Other services use the
+// tunnel with network_mode: "service:gluetun".
+var composeTemplate = template.Must(template.New("compose").Parse(`services:
+  gluetun:
+    image: {{.Image}}
+    cap_add:
+      - NET_ADMIN
+    devices:
+      - /dev/net/tun:/dev/net/tun
+    environment:
+{{- range .Env}}
+      {{.Name}}: "{{.Value}}"
+{{- end}}
+    restart: unless-stopped
+`))
+
+// secretTemplate is the template for the Kubernetes Secret, with the wg-quick configuration
+// (including its metadata header) under one key
+var secretTemplate = template.Must(template.New("secret").Funcs(template.FuncMap{"indent": yamlIndent}).Parse(`apiVersion: v1
+kind: Secret
+metadata:
+  name: {{.Name}}
+type: Opaque
+stringData:
+  {{.Key}}: |
+{{indent .Config}}
+`))
+
+// secretNamePattern matches Kubernetes object names (RFC 1123 subdomains)
+var secretNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// secretKeyPattern matches the keys allowed in a Secret's data
+var secretKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+func renderGluetun(data *configData) ([]byte, error) {
+	return renderTemplate(gluetunTemplate, data.Metadata, gluetunEnv(data))
+}
+
+func renderCompose(data *configData) ([]byte, error) {
+	return renderTemplate(composeTemplate, data.Metadata, struct {
+		Image string
+		Env   []envVar
+	}{gluetunImage, gluetunEnv(data)})
+}
+
+func renderKubernetesSecret(data *configData) ([]byte, error) {
+	name := data.Interface
+	if name == "" {
+		name = constants.DefaultInterfaceName
+	}
+	if len(name) > 253 || !secretNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("interface name %q is not a valid Kubernetes Secret name (use lowercase letters, digits, '-' and '.')", name)
+	}
+	key := data.SecretKey
+	if key == "" {
+		key = constants.DefaultSecretKey
+	}
+	if !secretKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("secret key %q may only contain letters, digits, '-', '_' and '.'", key)
+	}
+
+	config, err := renderWGQuick(data)
+	if err != nil {
+		return nil, err
+	}
+	return renderTemplate(secretTemplate, "", struct {
+		Name   string
+		Key    string
+		Config string
+	}{name, key, string(config)})
+}
+
+// yamlIndent indents the lines of a block scalar, leaving empty lines empty
+func yamlIndent(s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
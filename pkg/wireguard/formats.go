@@ -0,0 +1,309 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"protonvpn-wg-confgen/internal/api"
+	"protonvpn-wg-confgen/internal/config"
+)
+
+// Output format names
+const (
+	FormatWGQuick        = "wg-quick"
+	FormatWG             = "wg"
+	FormatJSON           = "json"
+	FormatNetworkd       = "networkd"
+	FormatNetworkManager = "networkmanager"
+	FormatOpenWrt        = "openwrt"
+	FormatRouterOS       = "routeros"
+	FormatOPNsense       = "opnsense"
+	FormatPfSense        = "pfsense"
+	FormatGluetun        = "gluetun"
+	FormatCompose        = "compose"
+	FormatKubernetes     = "kubernetes"
+	FormatSingBox        = "sing-box"
+	FormatXray           = "xray"
+	FormatMihomo         = "mihomo"
+	FormatWireproxy      = "wireproxy"
+)
+
+// outputFormat renders the configuration data in one output format.
+// Single-file formats implement render; formats that write several files implement files.
+type outputFormat struct {
+	suffix string // Replaces the -output extension when several formats are written
+	render func(data *configData) ([]byte, error)
+	files  func(data *configData, base string) ([]outputFile, error)
+}
+
+// outputFile is one file written by a multi-file format
+type outputFile struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+	group   string // Group to give the file when running as root, if it exists
+}
+
+// outputFormats is the registry of formats selectable with -format
+var outputFormats = map[string]outputFormat{
+	FormatWGQuick:        {suffix: ".conf", render: renderWGQuick},
+	FormatWG:             {suffix: ".setconf", render: renderWG},
+	FormatJSON:           {suffix: ".json", render: renderJSON},
+	FormatNetworkd:       {suffix: ".netdev", files: networkdFiles},
+	FormatNetworkManager: {suffix: ".nmconnection", files: networkManagerFiles},
+	FormatOpenWrt:        {suffix: ".uci.sh", render: renderOpenWrt},
+	FormatRouterOS:       {suffix: ".rsc", render: renderRouterOS},
+	FormatOPNsense:       {suffix: ".opnsense.xml", render: renderOPNsense},
+	FormatPfSense:        {suffix: ".pfsense.xml", render: renderPfSense},
+	FormatGluetun:        {suffix: ".env", render: renderGluetun},
+	FormatCompose:        {suffix: ".compose.yml", render: renderCompose},
+	FormatKubernetes:     {suffix: ".secret.yaml", render: renderKubernetesSecret},
+	FormatSingBox:        {suffix: ".sing-box.json", render: renderSingBox},
+	FormatXray:           {suffix: ".xray.json", render: renderXray},
+	FormatMihomo:         {suffix: ".mihomo.yaml", render: renderMihomo},
+	FormatWireproxy:      {suffix: ".wireproxy.conf", render: renderWireproxy},
+}
+
+// FormatNames returns the names of all output formats
+func FormatNames() []string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unknownFormatError(format string) error {
+	return fmt.Errorf("unknown output format: %s (expected one of %s)", format, strings.Join(FormatNames(), ", "))
+}
+
+// ResolveOutputs validates the -format outputs and fills in their file names. The first output
+// without an explicit file is written to -output; the others replace its extension with the
+// format's suffix (e.g., protonvpn.conf and protonvpn.json).
+func ResolveOutputs(cfg *config.Config) ([]config.Output, error) {
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []config.Output{{Format: FormatWGQuick}}
+	}
+
+	base := strings.TrimSuffix(cfg.OutputFile, filepath.Ext(cfg.OutputFile))
+	primaryUsed := false
+	seen := make(map[string]string)
+
+	resolved := make([]config.Output, 0, len(outputs))
+	for _, output := range outputs {
+		format, ok := outputFormats[output.Format]
+		if !ok {
+			return nil, unknownFormatError(output.Format)
+		}
+		if output.Path == "" && output.Format == FormatNetworkManager && cfg.NMConnectionDir != "" {
+			output.Path = filepath.Join(cfg.NMConnectionDir, filepath.Base(base)+format.suffix)
+		}
+		if output.Path == "" {
+			if primaryUsed {
+				output.Path = base + format.suffix
+			} else {
+				output.Path = cfg.OutputFile
+				primaryUsed = true
+			}
+		}
+		if format.files != nil {
+			// Multi-file formats name their files after the path without its extension
+			output.Path = strings.TrimSuffix(output.Path, filepath.Ext(output.Path)) + format.suffix
+		}
+		if previous, ok := seen[output.Path]; ok {
+			return nil, fmt.Errorf("output formats %s and %s would both be written to %s", previous, output.Format, output.Path)
+		}
+		seen[output.Path] = output.Format
+		resolved = append(resolved, output)
+	}
+	return resolved, nil
+}
+
+// OutputPaths returns the files written by Generate
+func OutputPaths(cfg *config.Config) ([]string, error) {
+	outputs, err := ResolveOutputs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(outputs))
+	for i, output := range outputs {
+		paths[i] = output.Path
+	}
+	return paths, nil
+}
+
+var templateFuncs = template.FuncMap{"join": strings.Join}
+
+// wgQuickTemplate is the template for wg-quick configurations
+var wgQuickTemplate = template.Must(template.New("wg-quick").Funcs(templateFuncs).Parse(`[Interface]
+PrivateKey = {{.PrivateKey}}
+Address = {{join .Addresses ", "}}
+DNS = {{join .DNS ", "}}
+
+[Peer]
+PublicKey = {{.PublicKey}}
+AllowedIPs = {{join .AllowedIPs ", "}}
+Endpoint = {{.Endpoint}}:{{.Port}}
+`))
+
+// wgTemplate is the template for wg setconf, which rejects the wg-quick Address and DNS keys
+var wgTemplate = template.Must(template.New("wg").Funcs(templateFuncs).Parse(`[Interface]
+PrivateKey = {{.PrivateKey}}
+
+[Peer]
+PublicKey = {{.PublicKey}}
+AllowedIPs = {{join .AllowedIPs ", "}}
+Endpoint = {{.Endpoint}}:{{.Port}}
+`))
+
+// wireproxyTemplate is the template for wireproxy, which runs the tunnel in userspace and
+// exposes it through local SOCKS5 and HTTP proxies
+var wireproxyTemplate = template.Must(template.New("wireproxy").Funcs(templateFuncs).Parse(`[Interface]
+PrivateKey = {{.PrivateKey}}
+Address = {{join .Addresses ", "}}
+DNS = {{join .DNS ", "}}
+
+[Peer]
+PublicKey = {{.PublicKey}}
+AllowedIPs = {{join .AllowedIPs ", "}}
+Endpoint = {{.Endpoint}}:{{.Port}}
+{{- range .Listeners}}
+{{if eq .Type "socks5"}}
+[Socks5]
+{{- else}}
+[http]
+{{- end}}
+BindAddress = {{.Address}}
+{{- end}}
+`))
+
+func renderWGQuick(data *configData) ([]byte, error) {
+	return renderTemplate(wgQuickTemplate, data.Metadata, data)
+}
+
+func renderWG(data *configData) ([]byte, error) {
+	return renderTemplate(wgTemplate, data.Metadata, data)
+}
+
+func renderWireproxy(data *configData) ([]byte, error) {
+	if len(data.Listeners) == 0 {
+		return nil, fmt.Errorf("wireproxy output requires at least one -proxy-listeners entry")
+	}
+	return renderTemplate(wireproxyTemplate, data.Metadata, data)
+}
+
+// renderTemplate executes a template after the metadata header
+func renderTemplate(tmpl *template.Template, metadata string, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(metadata)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonConfig is the document written by the json output format
+type jsonConfig struct {
+	Generated   time.Time        `json:"generated"`
+	Device      string           `json:"device,omitempty"`
+	Interface   jsonInterface    `json:"interface"`
+	Peer        jsonPeer         `json:"peer"`
+	Server      jsonServer       `json:"server"`
+	Certificate *jsonCertificate `json:"certificate,omitempty"`
+}
+
+type jsonInterface struct {
+	PrivateKey string   `json:"private_key"`
+	Addresses  []string `json:"addresses"`
+	DNS        []string `json:"dns"`
+}
+
+type jsonPeer struct {
+	PublicKey  string   `json:"public_key"`
+	AllowedIPs []string `json:"allowed_ips"`
+	Endpoint   string   `json:"endpoint"`
+}
+
+type jsonServer struct {
+	Name             string   `json:"name"`
+	EntryCountry     string   `json:"entry_country,omitempty"`
+	Country          string   `json:"country"`
+	City             string   `json:"city"`
+	Tier             string   `json:"tier"`
+	Load             int      `json:"load"`
+	Score            float64  `json:"score"`
+	Features         []string `json:"features"`
+	PhysicalServerID string   `json:"physical_server_id"`
+	EntryIP          string   `json:"entry_ip"`
+	ExitIP           string   `json:"exit_ip"`
+	Domain           string   `json:"domain,omitempty"`
+}
+
+type jsonCertificate struct {
+	Features  []string   `json:"features"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func renderJSON(data *configData) ([]byte, error) {
+	server, physicalServer := data.Server, data.PhysicalServer
+
+	doc := jsonConfig{
+		Generated: data.Generated.UTC().Truncate(time.Second),
+		Device:    data.DeviceName,
+		Interface: jsonInterface{PrivateKey: data.PrivateKey, Addresses: data.Addresses, DNS: data.DNS},
+		Peer: jsonPeer{
+			PublicKey:  data.PublicKey,
+			AllowedIPs: data.AllowedIPs,
+			Endpoint:   fmt.Sprintf("%s:%d", data.Endpoint, data.Port),
+		},
+		Server: jsonServer{
+			Name:             server.Name,
+			Country:          server.ExitCountry,
+			City:             server.City,
+			Tier:             api.GetTierName(server.Tier),
+			Load:             server.Load,
+			Score:            server.Score,
+			Features:         api.GetFeatureNames(server.Features),
+			PhysicalServerID: physicalServer.ID,
+			EntryIP:          physicalServer.EntryIP,
+			ExitIP:           physicalServer.ExitIP,
+			Domain:           physicalServer.Domain,
+		},
+	}
+	if server.EntryCountry != server.ExitCountry {
+		doc.Server.EntryCountry = server.EntryCountry
+	}
+	if doc.Server.Features == nil {
+		doc.Server.Features = []string{}
+	}
+	if len(data.CertFeatures) > 0 || !data.CertExpiry.IsZero() {
+		doc.Certificate = &jsonCertificate{Features: data.CertFeatures}
+		if doc.Certificate.Features == nil {
+			doc.Certificate.Features = []string{}
+		}
+		if !data.CertExpiry.IsZero() {
+			expiresAt := data.CertExpiry.UTC()
+			doc.Certificate.ExpiresAt = &expiresAt
+		}
+	}
+
+	return marshalJSON(doc)
+}
+
+// marshalJSON returns an indented JSON document ending with a newline
+func marshalJSON(doc interface{}) ([]byte, error) {
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
@@ -0,0 +1,138 @@
+package wireguard
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// networkdGroup is the group systemd-networkd runs as; it must be able to read PrivateKeyFile
+const networkdGroup = "systemd-network"
+
+// networkdData extends configData with the values derived for systemd-networkd
+type networkdData struct {
+	*configData
+	Name           string
+	PrivateKeyFile string
+	RoutingTable   int
+	FirewallMark   int
+	RuleFamily     string // Family= of the policy routing rules; empty without a default route
+}
+
+// netdevTemplate is the template for the .netdev file
+var netdevTemplate = template.Must(template.New("netdev").Funcs(templateFuncs).Parse(`[NetDev]
+Name={{.Name}}
+Kind=wireguard
+Description=ProtonVPN {{.Server.Name}}
+
+[WireGuard]
+PrivateKeyFile={{.PrivateKeyFile}}
+{{- if .RuleFamily}}
+FirewallMark={{.FirewallMark}}
+RouteTable={{.RoutingTable}}
+{{- else}}
+RouteTable=main
+{{- end}}
+
+[WireGuardPeer]
+PublicKey={{.PublicKey}}
+AllowedIPs={{join .AllowedIPs ","}}
+Endpoint={{.Endpoint}}:{{.Port}}
+`))
+
+// networkTemplate is the template for the .network file. With a default route, the policy
+// routing rules mirror wg-quick: everything but the tunnel's own marked packets uses the
+// tunnel's table, while more specific routes in the main table (e.g., the LAN) still apply.
+var networkTemplate = template.Must(template.New("network").Parse(`[Match]
+Name={{.Name}}
+
+[Network]
+{{- range .Addresses}}
+Address={{.}}
+{{- end}}
+{{- range .DNS}}
+DNS={{.}}
+{{- end}}
+{{- if .DNS}}
+Domains=~.
+DNSDefaultRoute=yes
+{{- end}}
+{{- if .RuleFamily}}
+
+[RoutingPolicyRule]
+Family={{.RuleFamily}}
+Table=main
+SuppressPrefixLength=0
+Priority=32764
+
+[RoutingPolicyRule]
+Family={{.RuleFamily}}
+FirewallMark={{.FirewallMark}}
+InvertRule=yes
+Table={{.RoutingTable}}
+Priority=32765
+{{- end}}
+`))
+
+// networkdFiles renders base.netdev, base.network and the base.key file referenced by PrivateKeyFile=
+func networkdFiles(data *configData, base string) ([]outputFile, error) {
+	keyFile, err := filepath.Abs(base + ".key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve key file path: %w", err)
+	}
+
+	nd := &networkdData{
+		configData:     data,
+		Name:           data.Interface,
+		PrivateKeyFile: keyFile,
+		RoutingTable:   constants.RoutingTable,
+		FirewallMark:   constants.FirewallMark,
+		RuleFamily:     defaultRouteFamily(data.AllowedIPs),
+	}
+	if nd.Name == "" {
+		nd.Name = constants.DefaultInterfaceName
+	}
+
+	netdev, err := renderTemplate(netdevTemplate, data.Metadata, nd)
+	if err != nil {
+		return nil, err
+	}
+	network, err := renderTemplate(networkTemplate, data.Metadata, nd)
+	if err != nil {
+		return nil, err
+	}
+
+	return []outputFile{
+		{path: keyFile, content: []byte(data.PrivateKey + "\n"), mode: 0o640, group: networkdGroup},
+		{path: base + ".netdev", content: netdev, mode: 0o644},
+		{path: base + ".network", content: network, mode: 0o644},
+	}, nil
+}
+
+// defaultRouteFamily returns the address family of the default routes in allowedIPs
+// ("ipv4", "ipv6" or "both"), or "" when the tunnel only carries specific prefixes
+func defaultRouteFamily(allowedIPs []string) string {
+	var ipv4, ipv6 bool
+	for _, prefix := range allowedIPs {
+		switch strings.TrimSpace(prefix) {
+		case "0.0.0.0/0":
+			ipv4 = true
+		case "::/0":
+			ipv6 = true
+		}
+	}
+
+	switch {
+	case ipv4 && ipv6:
+		return "both"
+	case ipv4:
+		return "ipv4"
+	case ipv6:
+		return "ipv6"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,176 @@
+package wireguard
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- only used for name-based UUIDs
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// networkManagerData extends configData with the values of a NetworkManager keyfile
+type networkManagerData struct {
+	*configData
+	ID          string
+	UUID        string
+	Name        string
+	IPv4        []string
+	IPv6        []string
+	DNS4        []string
+	DNS6        []string
+	DNSPriority int
+}
+
+// networkManagerDNSPriority makes the tunnel's DNS servers the only ones used while it is up
+const networkManagerDNSPriority = -50
+
+// networkManagerTemplate is the template for .nmconnection keyfiles. NetworkManager adds
+// wg-quick style policy routing itself when the allowed IPs contain a default route.
+var networkManagerTemplate = template.Must(template.New("networkmanager").Funcs(template.FuncMap{
+	"list": nmList,
+	"inc":  func(i int) int { return i + 1 },
+}).Parse(`[connection]
+id={{.ID}}
+uuid={{.UUID}}
+type=wireguard
+interface-name={{.Name}}
+autoconnect={{.Autoconnect}}
+
+[wireguard]
+private-key={{.PrivateKey}}
+
+[wireguard-peer.{{.PublicKey}}]
+endpoint={{.Endpoint}}:{{.Port}}
+allowed-ips={{list .AllowedIPs}}
+
+[ipv4]
+method=manual
+{{- range $i, $address := .IPv4}}
+address{{inc $i}}={{$address}}
+{{- end}}
+{{- if .DNS4}}
+dns={{list .DNS4}}
+dns-priority={{$.DNSPriority}}
+dns-search=~.;
+{{- end}}
+
+[ipv6]
+{{- if .IPv6}}
+method=manual
+{{- range $i, $address := .IPv6}}
+address{{inc $i}}={{$address}}
+{{- end}}
+{{- if .DNS6}}
+dns={{list .DNS6}}
+dns-priority={{$.DNSPriority}}
+dns-search=~.;
+{{- end}}
+{{- else}}
+method=disabled
+{{- end}}
+`))
+
+// networkManagerFiles renders base.nmconnection with mode 0600, which NetworkManager requires.
+// The UUID of an existing keyfile is kept so that NetworkManager updates the connection in place.
+func networkManagerFiles(data *configData, base string) ([]outputFile, error) {
+	path := base + ".nmconnection"
+
+	uuid, err := existingConnectionUUID(path)
+	if err != nil {
+		return nil, err
+	}
+	if uuid == "" {
+		if uuid, err = newUUID(); err != nil {
+			return nil, err
+		}
+	}
+
+	nm := &networkManagerData{
+		configData:  data,
+		ID:          filepath.Base(base),
+		UUID:        uuid,
+		Name:        data.DeviceIface,
+		DNSPriority: networkManagerDNSPriority,
+	}
+	nm.IPv4, nm.IPv6 = splitByFamily(data.Addresses)
+	nm.DNS4, nm.DNS6 = splitByFamily(data.DNS)
+
+	content, err := renderTemplate(networkManagerTemplate, data.Metadata, nm)
+	if err != nil {
+		return nil, err
+	}
+	return []outputFile{{path: path, content: content, mode: 0o600}}, nil
+}
+
+// existingConnectionUUID returns the [connection] uuid of an existing keyfile, or "" if there is none
+func existingConnectionUUID(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read existing connection: %w", err)
+	}
+
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "uuid="); ok && section == "[connection]" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate connection UUID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// uuidNamespace is the namespace of the name-based UUIDs of this tool
+var uuidNamespace = [16]byte{0x5b, 0x1e, 0x6c, 0x0e, 0x2f, 0x4a, 0x4d, 0x8b, 0x9a, 0x61, 0x3c, 0x7e, 0x52, 0x0d, 0xa4, 0x19}
+
+// nameUUID returns a name-based (version 5) UUID, which is the same for the same name on every run
+func nameUUID(name string) string {
+	hash := sha1.New() // #nosec G401 -- RFC 9562 defines version 5 UUIDs with SHA-1
+	hash.Write(uuidNamespace[:])
+	hash.Write([]byte(name))
+	b := hash.Sum(nil)[:16]
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// splitByFamily splits addresses or prefixes into IPv4 and IPv6 ones
+func splitByFamily(values []string) (ipv4, ipv6 []string) {
+	for _, value := range values {
+		host, _, _ := strings.Cut(value, "/")
+		if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			ipv6 = append(ipv6, value)
+		} else {
+			ipv4 = append(ipv4, value)
+		}
+	}
+	return ipv4, ipv6
+}
+
+// nmList formats a keyfile list, which NetworkManager terminates with a semicolon
+func nmList(values []string) string {
+	return strings.Join(values, ";") + ";"
+}
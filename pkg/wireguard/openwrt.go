@@ -0,0 +1,89 @@
+package wireguard
+
+import (
+	"fmt"
+	"text/template"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// maxFirewallZoneName is the longest zone name fw3/fw4 accept
+const maxFirewallZoneName = 11
+
+// openWrtData extends configData with the UCI section names
+type openWrtData struct {
+	*configData
+	Name string // Interface section, also used for the peer section and firewall zone
+}
+
+// openWrtTemplate is a shell script feeding a uci batch. Every section is named after the
+// interface and deleted before it is recreated, so applying the script again after a
+// rotation updates the interface in place.
+var openWrtTemplate = template.Must(template.New("openwrt").Parse(`#!/bin/sh
+{{.Metadata}}# Apply with: sh <this file> && /etc/init.d/network reload{{if .Firewall}} && /etc/init.d/firewall reload{{end}}
+uci -q batch <<'EOF'
+delete network.{{.Name}}
+set network.{{.Name}}=interface
+set network.{{.Name}}.proto='wireguard'
+set network.{{.Name}}.private_key='{{.PrivateKey}}'
+{{- range .Addresses}}
+add_list network.{{$.Name}}.addresses='{{.}}'
+{{- end}}
+{{- range .DNS}}
+add_list network.{{$.Name}}.dns='{{.}}'
+{{- end}}
+delete network.{{.Name}}_peer
+set network.{{.Name}}_peer=wireguard_{{.Name}}
+set network.{{.Name}}_peer.description='ProtonVPN {{.Server.Name}}'
+set network.{{.Name}}_peer.public_key='{{.PublicKey}}'
+{{- range .AllowedIPs}}
+add_list network.{{$.Name}}_peer.allowed_ips='{{.}}'
+{{- end}}
+set network.{{.Name}}_peer.endpoint_host='{{.Endpoint}}'
+set network.{{.Name}}_peer.endpoint_port='{{.Port}}'
+set network.{{.Name}}_peer.route_allowed_ips='1'
+commit network
+{{- if .Firewall}}
+delete firewall.{{.Name}}
+set firewall.{{.Name}}=zone
+set firewall.{{.Name}}.name='{{.Name}}'
+set firewall.{{.Name}}.input='REJECT'
+set firewall.{{.Name}}.output='ACCEPT'
+set firewall.{{.Name}}.forward='REJECT'
+set firewall.{{.Name}}.masq='1'
+set firewall.{{.Name}}.mtu_fix='1'
+add_list firewall.{{.Name}}.network='{{.Name}}'
+delete firewall.{{.Name}}_lan
+set firewall.{{.Name}}_lan=forwarding
+set firewall.{{.Name}}_lan.src='lan'
+set firewall.{{.Name}}_lan.dest='{{.Name}}'
+commit firewall
+{{- end}}
+EOF
+`))
+
+func renderOpenWrt(data *configData) ([]byte, error) {
+	ow := &openWrtData{configData: data, Name: data.Interface}
+	if ow.Name == "" {
+		ow.Name = constants.DefaultInterfaceName
+	}
+
+	if !isUCIName(ow.Name) {
+		return nil, fmt.Errorf("interface name %q is not a valid UCI section name (use letters, digits and '_')", ow.Name)
+	}
+	if data.Firewall && len(ow.Name) > maxFirewallZoneName {
+		return nil, fmt.Errorf("interface name %q is too long for a firewall zone (at most %d characters)", ow.Name, maxFirewallZoneName)
+	}
+
+	return renderTemplate(openWrtTemplate, "", ow)
+}
+
+// isUCIName reports whether name is a valid UCI section name
+func isUCIName(name string) bool {
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,216 @@
+package wireguard
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// opnSenseConfig is the WireGuard section of an OPNsense config.xml, which can be restored
+// on its own under System > Configuration > Backups. OPNsense calls the local instance a
+// "server" and its peers "clients".
+type opnSenseConfig struct {
+	XMLName   xml.Name          `xml:"opnsense"`
+	WireGuard opnSenseWireGuard `xml:"OPNsense>wireguard"`
+}
+
+type opnSenseWireGuard struct {
+	Enabled string           `xml:"general>enabled"`
+	Servers []opnSenseServer `xml:"server>servers>server"`
+	Clients []opnSenseClient `xml:"client>clients>client"`
+}
+
+type opnSenseServer struct {
+	UUID          string `xml:"uuid,attr"`
+	Enabled       string `xml:"enabled"`
+	Name          string `xml:"name"`
+	Instance      int    `xml:"instance"`
+	PublicKey     string `xml:"pubkey"`
+	PrivateKey    string `xml:"privkey"`
+	DNS           string `xml:"dns"`
+	TunnelAddress string `xml:"tunneladdress"`
+	DisableRoutes string `xml:"disableroutes"`
+	Peers         string `xml:"peers"`
+}
+
+type opnSenseClient struct {
+	UUID          string `xml:"uuid,attr"`
+	Enabled       string `xml:"enabled"`
+	Name          string `xml:"name"`
+	PublicKey     string `xml:"pubkey"`
+	TunnelAddress string `xml:"tunneladdress"`
+	ServerAddress string `xml:"serveraddress"`
+	ServerPort    int    `xml:"serverport"`
+}
+
+// pfSenseConfig is the section the pfSense WireGuard package stores in config.xml, which
+// can be restored on its own under Diagnostics > Backup & Restore
+type pfSenseConfig struct {
+	XMLName xml.Name        `xml:"pfsense"`
+	Tunnels []pfSenseTunnel `xml:"installedpackages>wireguard>tunnels>item"`
+	Peers   []pfSensePeer   `xml:"installedpackages>wireguard>peers>item"`
+	Enable  string          `xml:"installedpackages>wireguard>config>enable"`
+}
+
+type pfSenseTunnel struct {
+	Name        string          `xml:"name"`
+	Enabled     string          `xml:"enabled"`
+	Description string          `xml:"descr"`
+	ListenPort  int             `xml:"listenport"`
+	PrivateKey  string          `xml:"privatekey"`
+	PublicKey   string          `xml:"publickey"`
+	Addresses   []pfSensePrefix `xml:"addresses>row"`
+}
+
+type pfSensePeer struct {
+	Enabled     string          `xml:"enabled"`
+	Tunnel      string          `xml:"tun"`
+	Description string          `xml:"descr"`
+	Endpoint    string          `xml:"endpoint"`
+	Port        int             `xml:"port"`
+	PublicKey   string          `xml:"publickey"`
+	AllowedIPs  []pfSensePrefix `xml:"allowedips>row"`
+}
+
+type pfSensePrefix struct {
+	Address     string `xml:"address"`
+	Mask        string `xml:"mask"`
+	Description string `xml:"descr"`
+}
+
+// pfSenseDefaultTunnel is used when -interface is not a pfSense tunnel name (tun_wgN)
+const pfSenseDefaultTunnel = "tun_wg0"
+
+func renderOPNsense(data *configData) ([]byte, error) {
+	publicKey, err := x25519PublicKey(data.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	name := data.Interface
+	if name == "" {
+		name = constants.DefaultInterfaceName
+	}
+
+	// Stable UUIDs let a re-imported file replace the instance and peer of the previous run
+	serverUUID := nameUUID("opnsense-server/" + name + "/" + data.KeyProfile)
+	clientUUID := nameUUID("opnsense-client/" + name + "/" + data.KeyProfile)
+
+	doc := opnSenseConfig{WireGuard: opnSenseWireGuard{
+		Enabled: "1",
+		Servers: []opnSenseServer{{
+			UUID:          serverUUID,
+			Enabled:       "1",
+			Name:          name,
+			PublicKey:     publicKey,
+			PrivateKey:    data.PrivateKey,
+			DNS:           strings.Join(data.DNS, ","),
+			TunnelAddress: strings.Join(data.Addresses, ","),
+			DisableRoutes: "0",
+			Peers:         clientUUID,
+		}},
+		Clients: []opnSenseClient{{
+			UUID:          clientUUID,
+			Enabled:       "1",
+			Name:          "ProtonVPN " + data.Server.Name,
+			PublicKey:     data.PublicKey,
+			TunnelAddress: strings.Join(data.AllowedIPs, ","),
+			ServerAddress: data.Endpoint,
+			ServerPort:    data.Port,
+		}},
+	}}
+	return marshalXML(data.Metadata, doc)
+}
+
+func renderPfSense(data *configData) ([]byte, error) {
+	publicKey, err := x25519PublicKey(data.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := pfSensePrefixes(data.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	allowedIPs, err := pfSensePrefixes(data.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel := data.Interface
+	if !strings.HasPrefix(tunnel, "tun_wg") {
+		tunnel = pfSenseDefaultTunnel
+	}
+
+	doc := pfSenseConfig{
+		Tunnels: []pfSenseTunnel{{
+			Name:        tunnel,
+			Enabled:     "yes",
+			Description: "ProtonVPN",
+			ListenPort:  constants.WireGuardPort,
+			PrivateKey:  data.PrivateKey,
+			PublicKey:   publicKey,
+			Addresses:   addresses,
+		}},
+		Peers: []pfSensePeer{{
+			Enabled:     "yes",
+			Tunnel:      tunnel,
+			Description: "ProtonVPN " + data.Server.Name,
+			Endpoint:    data.Endpoint,
+			Port:        data.Port,
+			PublicKey:   data.PublicKey,
+			AllowedIPs:  allowedIPs,
+		}},
+		Enable: "on",
+	}
+	return marshalXML(data.Metadata, doc)
+}
+
+// pfSensePrefixes splits prefixes into the address and mask rows pfSense stores
+func pfSensePrefixes(prefixes []string) ([]pfSensePrefix, error) {
+	rows := make([]pfSensePrefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		ip, network, err := net.ParseCIDR(strings.TrimSpace(prefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q: %w", prefix, err)
+		}
+		ones, _ := network.Mask.Size()
+		rows = append(rows, pfSensePrefix{Address: ip.String(), Mask: strconv.Itoa(ones)})
+	}
+	return rows, nil
+}
+
+// marshalXML writes an XML document with the metadata header as a comment
+func marshalXML(metadata string, doc interface{}) ([]byte, error) {
+	content, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal XML: %w", err)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(xml.Header)
+	if metadata != "" {
+		// "--" is not allowed inside XML comments
+		buf.WriteString("<!--\n" + strings.ReplaceAll(metadata, "--", "- -") + "-->\n")
+	}
+	buf.Write(content)
+	buf.WriteString("\n")
+	return []byte(buf.String()), nil
+}
+
+// x25519PublicKey derives the WireGuard public key of a base64 private key
+func x25519PublicKey(privateKey string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
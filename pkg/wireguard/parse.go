@@ -0,0 +1,189 @@
+package wireguard
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsedConfig is a generated WireGuard configuration read back from disk,
+// including the metadata header written by buildMetadata
+type ParsedConfig struct {
+	Generated           time.Time `json:"generated"`
+	Device              string    `json:"device,omitempty"`
+	CertificateFeatures []string  `json:"certificate_features,omitempty"`
+	CertificateExpires  time.Time `json:"certificate_expires"` // As generated; the key store has the current expiry
+
+	ServerName     string   `json:"server_name"`
+	Country        string   `json:"country"`
+	City           string   `json:"city"`
+	Tier           string   `json:"tier"`
+	Load           int      `json:"load"`
+	Score          float64  `json:"score"`
+	ServerFeatures []string `json:"server_features,omitempty"`
+
+	PhysicalServerID string `json:"physical_server_id"`
+	EntryIP          string `json:"entry_ip"`
+	ExitIP           string `json:"exit_ip"`
+	SecureCore       string `json:"secure_core,omitempty"` // "entry → exit" for Secure Core servers
+
+	PrivateKey string `json:"-"` // Never printed; inspect only compares it with the stored keys
+	Address    string `json:"address"`
+	DNS        string `json:"dns"`
+	PublicKey  string `json:"public_key"`
+	AllowedIPs string `json:"allowed_ips"`
+	Endpoint   string `json:"endpoint"`
+}
+
+// ParseConfigFile reads and parses a generated WireGuard configuration file
+func ParseConfigFile(path string) (*ParsedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses a generated WireGuard configuration.
+// Header lines that are missing (e.g. in configs from other tools) are left empty.
+func ParseConfig(data []byte) (*ParsedConfig, error) {
+	parsed := &ParsedConfig{}
+	section := ""
+	headerSection := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			if section == "" {
+				headerSection = parsed.parseHeaderLine(strings.TrimSpace(strings.TrimPrefix(line, "#")), headerSection)
+			}
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid config line: %s", line)
+			}
+			parsed.setValue(section, strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if parsed.PrivateKey == "" {
+		return nil, fmt.Errorf("no [Interface] PrivateKey found")
+	}
+	return parsed, nil
+}
+
+// parseHeaderLine parses one metadata comment and returns the current header section
+func (p *ParsedConfig) parseHeaderLine(line, headerSection string) string {
+	switch {
+	case line == "Server Information:", line == "Physical Server:":
+		return line
+	case strings.HasPrefix(line, "Secure Core Routing:"):
+		p.SecureCore = strings.TrimSpace(strings.TrimPrefix(line, "Secure Core Routing:"))
+		return headerSection
+	}
+
+	key, value, ok := strings.Cut(strings.TrimPrefix(line, "- "), ":")
+	if !ok {
+		return headerSection
+	}
+	value = strings.TrimSpace(value)
+
+	switch headerSection {
+	case "Server Information:":
+		p.setServerValue(key, value)
+	case "Physical Server:":
+		switch key {
+		case "ID":
+			p.PhysicalServerID = value
+		case "Entry IP":
+			p.EntryIP = value
+		case "Exit IP":
+			p.ExitIP = value
+		}
+	default:
+		switch key {
+		case "Generated":
+			p.Generated, _ = time.Parse(metadataTimeFormat, value)
+		case "Device":
+			p.Device = value
+		case "Certificate Features":
+			p.CertificateFeatures = splitList(value)
+		case "Certificate Expires":
+			p.CertificateExpires, _ = time.Parse(metadataTimeFormat, value)
+		}
+	}
+	return headerSection
+}
+
+func (p *ParsedConfig) setServerValue(key, value string) {
+	switch key {
+	case "Name":
+		p.ServerName = value
+	case "Country":
+		p.Country = value
+	case "City":
+		p.City = value
+	case "Tier":
+		p.Tier = value
+	case "Load":
+		p.Load, _ = strconv.Atoi(strings.TrimSuffix(value, "%"))
+	case "Score":
+		p.Score, _ = strconv.ParseFloat(value, 64)
+	case "Features":
+		p.ServerFeatures = splitList(value)
+	}
+}
+
+func (p *ParsedConfig) setValue(section, key, value string) {
+	switch section + key {
+	case "[Interface]PrivateKey":
+		p.PrivateKey = value
+	case "[Interface]Address":
+		p.Address = value
+	case "[Interface]DNS":
+		p.DNS = value
+	case "[Peer]PublicKey":
+		p.PublicKey = value
+	case "[Peer]AllowedIPs":
+		p.AllowedIPs = value
+	case "[Peer]Endpoint":
+		p.Endpoint = value
+	}
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// StripComments removes the comment lines (such as the metadata header) from a configuration
+// and the empty lines they leave at its start
+func StripComments(data []byte) []byte {
+	var buf bytes.Buffer
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || (trimmed == "" && buf.Len() == 0) {
+			continue
+		}
+		buf.WriteString(line)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,209 @@
+package wireguard
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// proxyGroupTag is the tag of the selectable group; every member's tag starts with proxyTagPrefix
+const (
+	proxyGroupTag  = "proton"
+	proxyTagPrefix = "proton-"
+)
+
+// proxyMember is one server of a proxy format, tagged after the server name (e.g., proton-ch-1)
+type proxyMember struct {
+	Tag       string
+	Endpoint  string
+	Port      int
+	PublicKey string
+}
+
+// proxyMembers returns the selected server followed by the rest of the group
+func proxyMembers(data *configData) []proxyMember {
+	members := []proxyMember{{
+		Tag:       proxyTag(data.Server.Name),
+		Endpoint:  data.Endpoint,
+		Port:      data.Port,
+		PublicKey: data.PublicKey,
+	}}
+	for _, member := range data.Group {
+		members = append(members, proxyMember{
+			Tag:       proxyTag(member.Server.Name),
+			Endpoint:  member.PhysicalServer.EntryIP,
+			Port:      data.Port,
+			PublicKey: member.PhysicalServer.X25519PublicKey,
+		})
+	}
+	return members
+}
+
+// proxyTag turns a server name into a tag usable by every proxy platform
+func proxyTag(serverName string) string {
+	return proxyTagPrefix + strings.ToLower(strings.NewReplacer("#", "-", " ", "-").Replace(serverName))
+}
+
+// singBoxConfig holds the endpoints (sing-box 1.11 or later) and the selector outbound
+type singBoxConfig struct {
+	Endpoints []singBoxEndpoint `json:"endpoints"`
+	Outbounds []singBoxSelector `json:"outbounds,omitempty"`
+}
+
+type singBoxEndpoint struct {
+	Type       string        `json:"type"`
+	Tag        string        `json:"tag"`
+	Address    []string      `json:"address"`
+	PrivateKey string        `json:"private_key"`
+	Peers      []singBoxPeer `json:"peers"`
+}
+
+type singBoxPeer struct {
+	Address    string   `json:"address"`
+	Port       int      `json:"port"`
+	PublicKey  string   `json:"public_key"`
+	AllowedIPs []string `json:"allowed_ips"`
+}
+
+type singBoxSelector struct {
+	Type      string   `json:"type"`
+	Tag       string   `json:"tag"`
+	Outbounds []string `json:"outbounds"`
+}
+
+func renderSingBox(data *configData) ([]byte, error) {
+	members := proxyMembers(data)
+
+	var doc singBoxConfig
+	tags := make([]string, 0, len(members))
+	for _, member := range members {
+		doc.Endpoints = append(doc.Endpoints, singBoxEndpoint{
+			Type:       "wireguard",
+			Tag:        member.Tag,
+			Address:    data.Addresses,
+			PrivateKey: data.PrivateKey,
+			Peers: []singBoxPeer{{
+				Address:    member.Endpoint,
+				Port:       member.Port,
+				PublicKey:  member.PublicKey,
+				AllowedIPs: data.AllowedIPs,
+			}},
+		})
+		tags = append(tags, member.Tag)
+	}
+	if len(members) > 1 {
+		doc.Outbounds = []singBoxSelector{{Type: "selector", Tag: proxyGroupTag, Outbounds: tags}}
+	}
+	return marshalJSON(doc)
+}
+
+// xrayConfig holds the wireguard outbounds. Xray uses the first outbound by default; the group
+// gets no balancer because the servers share one key, which may only be connected to one at a time.
+type xrayConfig struct {
+	Outbounds []xrayOutbound `json:"outbounds"`
+}
+
+type xrayOutbound struct {
+	Tag      string       `json:"tag"`
+	Protocol string       `json:"protocol"`
+	Settings xraySettings `json:"settings"`
+}
+
+type xraySettings struct {
+	SecretKey string     `json:"secretKey"`
+	Address   []string   `json:"address"`
+	Peers     []xrayPeer `json:"peers"`
+}
+
+type xrayPeer struct {
+	PublicKey  string   `json:"publicKey"`
+	Endpoint   string   `json:"endpoint"`
+	AllowedIPs []string `json:"allowedIPs"`
+}
+
+func renderXray(data *configData) ([]byte, error) {
+	members := proxyMembers(data)
+
+	var doc xrayConfig
+	for _, member := range members {
+		doc.Outbounds = append(doc.Outbounds, xrayOutbound{
+			Tag:      member.Tag,
+			Protocol: "wireguard",
+			Settings: xraySettings{
+				SecretKey: data.PrivateKey,
+				Address:   data.Addresses,
+				Peers: []xrayPeer{{
+					PublicKey:  member.PublicKey,
+					Endpoint:   fmt.Sprintf("%s:%d", member.Endpoint, member.Port),
+					AllowedIPs: data.AllowedIPs,
+				}},
+			},
+		})
+	}
+	return marshalJSON(doc)
+}
+
+// mihomoData extends configData with the values of a Mihomo (Clash Meta) proxy list
+type mihomoData struct {
+	*configData
+	Members  []proxyMember
+	GroupTag string
+	IPv4     string
+	IPv6     string
+}
+
+// mihomoTemplate is the template for the proxies and proxy-groups of a Mihomo configuration
+var mihomoTemplate = template.Must(template.New("mihomo").Funcs(template.FuncMap{"list": yamlFlowList}).Parse(`proxies:
+{{- range .Members}}
+  - name: "{{.Tag}}"
+    type: wireguard
+    server: {{.Endpoint}}
+    port: {{.Port}}
+    ip: {{$.IPv4}}
+{{- if $.IPv6}}
+    ipv6: {{$.IPv6}}
+{{- end}}
+    private-key: "{{$.PrivateKey}}"
+    public-key: "{{.PublicKey}}"
+    allowed-ips: {{list $.AllowedIPs}}
+    udp: true
+{{- if $.DNS}}
+    remote-dns-resolve: true
+    dns: {{list $.DNS}}
+{{- end}}
+{{- end}}
+{{- if gt (len .Members) 1}}
+
+proxy-groups:
+  - name: "{{.GroupTag}}"
+    type: select
+    proxies:
+{{- range .Members}}
+      - "{{.Tag}}"
+{{- end}}
+{{- end}}
+`))
+
+func renderMihomo(data *configData) ([]byte, error) {
+	md := &mihomoData{configData: data, Members: proxyMembers(data), GroupTag: proxyGroupTag}
+
+	// Mihomo takes the tunnel addresses without their prefix length
+	ipv4, ipv6 := splitByFamily(data.Addresses)
+	if len(ipv4) > 0 {
+		md.IPv4, _, _ = strings.Cut(ipv4[0], "/")
+	}
+	if len(ipv6) > 0 {
+		md.IPv6, _, _ = strings.Cut(ipv6[0], "/")
+	}
+
+	return renderTemplate(mihomoTemplate, data.Metadata, md)
+}
+
+// yamlFlowList formats values as a YAML flow sequence of quoted strings
+func yamlFlowList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = `"` + value + `"`
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,48 @@
+package wireguard
+
+import (
+	"text/template"
+
+	"protonvpn-wg-confgen/internal/constants"
+)
+
+// routerOSData extends configData with the values of a RouterOS script
+type routerOSData struct {
+	*configData
+	Name    string
+	Comment string // Marks everything the script manages, so rotations find and update it
+	IPv4    []string
+	IPv6    []string
+}
+
+// routerOSTemplate is the template for RouterOS v7 scripts. Every command uses its full menu
+// path because an imported script runs each line on its own. The interface and peer are
+// updated in place when they exist; the addresses are replaced.
+var routerOSTemplate = template.Must(template.New("routeros").Funcs(templateFuncs).Parse(`# Apply with: /import file-name=<this file>
+:if ([:len [/interface wireguard find where comment="{{.Comment}}"]] = 0) do={ /interface wireguard add name="{{.Name}}" private-key="{{.PrivateKey}}" comment="{{.Comment}}" } else={ /interface wireguard set [find where comment="{{.Comment}}"] name="{{.Name}}" private-key="{{.PrivateKey}}" }
+:if ([:len [/interface wireguard peers find where comment="{{.Comment}}"]] = 0) do={ /interface wireguard peers add interface="{{.Name}}" public-key="{{.PublicKey}}" endpoint-address={{.Endpoint}} endpoint-port={{.Port}} allowed-address={{join .AllowedIPs ","}} comment="{{.Comment}}" } else={ /interface wireguard peers set [find where comment="{{.Comment}}"] interface="{{.Name}}" public-key="{{.PublicKey}}" endpoint-address={{.Endpoint}} endpoint-port={{.Port}} allowed-address={{join .AllowedIPs ","}} }
+/ip address remove [find where comment="{{.Comment}}"]
+{{- range .IPv4}}
+/ip address add address={{.}} interface="{{$.Name}}" comment="{{$.Comment}}"
+{{- end}}
+{{- if .IPv6}}
+/ipv6 address remove [find where comment="{{.Comment}}"]
+{{- range .IPv6}}
+/ipv6 address add address={{.}} interface="{{$.Name}}" advertise=no comment="{{$.Comment}}"
+{{- end}}
+{{- end}}
+{{- if .DNS}}
+/ip dns set servers={{join .DNS ","}}
+{{- end}}
+`))
+
+func renderRouterOS(data *configData) ([]byte, error) {
+	ros := &routerOSData{configData: data, Name: data.Interface}
+	if ros.Name == "" {
+		ros.Name = constants.DefaultInterfaceName
+	}
+	ros.Comment = "protonvpn-wg-confgen " + ros.Name
+	ros.IPv4, ros.IPv6 = splitByFamily(data.Addresses)
+
+	return renderTemplate(routerOSTemplate, data.Metadata, ros)
+}